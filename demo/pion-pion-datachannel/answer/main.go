@@ -1,181 +1,93 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
-	"sync"
 	"time"
 
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/iceserver"
+	"webrtc-demo/pkg/peer"
 	"webrtc-demo/pkg/signal"
+	"webrtc-demo/pkg/signaling"
 
 	"github.com/pion/webrtc/v3"
 )
 
-func signalCandidate(addr string, c *webrtc.ICECandidate) error {
-	payload := []byte(c.ToJSON().Candidate)
-	resp, err := http.Post(fmt.Sprintf("http://%s/candidate", addr), // nolint:noctx
-		"application/json; charset=utf-8", bytes.NewReader(payload))
-	if err != nil {
-		return err
-	}
-
-	if closeErr := resp.Body.Close(); closeErr != nil {
-		return closeErr
-	}
-
-	return nil
-}
-
 func main() { // nolint:gocognit
-	offerAddr := flag.String("offer-address", "localhost:50000", "Address that the Offer HTTP server is hosted on.")
 	answerAddr := flag.String("answer-address", ":60000", "Address that the Answer HTTP server is hosted on.")
+	configPath := flag.String("config", "", "TOML config whose api_key/api_secret (or token) authenticate signaling, open signaling when empty.")
+	iceFlags := config.NewICEFlags()
 	flag.Parse()
 
-	var candidatesMux sync.Mutex
-	pendingCandidates := make([]*webrtc.ICECandidate, 0)
-	// Everything below is the Pion WebRTC API! Thanks for using it ❤️.
-
-	// Prepare the configuration
-	config := webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{
-				URLs: []string{"stun:stun.l.google.com:19302"},
-			},
-		},
+	cfg, err := config.GetOptionalConfig(*configPath)
+	if err != nil {
+		panic(err)
 	}
+	// Flags given on the command line win over the config file and the environment
+	config.Override(&cfg, &cfg.Signaling.ListenAddress, "answer-address", *answerAddr)
+	iceFlags.Apply(&cfg)
 
-	// Create a new RTCPeerConnection
-	peerConnection, err := webrtc.NewPeerConnection(config)
+	// Host the STUN and TURN servers of the [stun] and [turn] tables of the config,
+	// for peers that have none of their own or sit behind symmetric NAT
+	iceServers, err := iceserver.Start(cfg)
 	if err != nil {
 		panic(err)
 	}
 	defer func() {
-		if err := peerConnection.Close(); err != nil {
-			fmt.Printf("cannot close peerConnection: %v\n", err)
+		if err := iceServers.Close(); err != nil {
+			fmt.Printf("cannot close ICE servers: %v\n", err)
 		}
 	}()
 
-	// When an ICE candidate is available send to the other Pion instance
-	// the other Pion instance will add this candidate by calling AddICECandidate
-	peerConnection.OnICECandidate(func(c *webrtc.ICECandidate) {
-		if c == nil {
-			return
-		}
-
-		candidatesMux.Lock()
-		defer candidatesMux.Unlock()
-
-		desc := peerConnection.RemoteDescription()
-		if desc == nil {
-			pendingCandidates = append(pendingCandidates, c)
-		} else if onICECandidateErr := signalCandidate(*offerAddr, c); onICECandidateErr != nil {
-			panic(onICECandidateErr)
-		}
-	})
-
-	// A HTTP handler that allows the other Pion instance to send us ICE candidates
-	// This allows us to add ICE candidates faster, we don't have to wait for STUN or TURN
-	// candidates which may be slower
-	http.HandleFunc("/candidate", func(w http.ResponseWriter, r *http.Request) {
-		candidate, candidateErr := ioutil.ReadAll(r.Body)
-		if candidateErr != nil {
-			panic(candidateErr)
-		}
-		if candidateErr := peerConnection.AddICECandidate(webrtc.ICECandidateInit{Candidate: string(candidate)}); candidateErr != nil {
-			panic(candidateErr)
-		}
-	})
-
-	// A HTTP handler that processes a SessionDescription given to us from the other Pion process
-	http.HandleFunc("/sdp", func(w http.ResponseWriter, r *http.Request) {
-		sdp := webrtc.SessionDescription{}
-		if err := json.NewDecoder(r.Body).Decode(&sdp); err != nil {
-			panic(err)
-		}
-
-		if err := peerConnection.SetRemoteDescription(sdp); err != nil {
-			panic(err)
-		}
-
-		// Create an answer to send to the other process
-		answer, err := peerConnection.CreateAnswer(nil)
-		if err != nil {
-			panic(err)
-		}
-
-		// Send our answer to the HTTP server listening in the other process
-		payload, err := json.Marshal(answer)
-		if err != nil {
-			panic(err)
-		}
-		resp, err := http.Post(fmt.Sprintf("http://%s/sdp", *offerAddr), "application/json; charset=utf-8", bytes.NewReader(payload)) // nolint:noctx
-		if err != nil {
-			panic(err)
-		} else if closeErr := resp.Body.Close(); closeErr != nil {
-			panic(closeErr)
-		}
-
-		// Sets the LocalDescription, and starts our UDP listeners
-		err = peerConnection.SetLocalDescription(answer)
-		if err != nil {
-			panic(err)
-		}
-
-		candidatesMux.Lock()
-		for _, c := range pendingCandidates {
-			onICECandidateErr := signalCandidate(*offerAddr, c)
-			if onICECandidateErr != nil {
-				panic(onICECandidateErr)
-			}
-		}
-		candidatesMux.Unlock()
-	})
-
-	// Set the handler for Peer connection state
-	// This will notify you when the peer has connected/disconnected
-	peerConnection.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
-		fmt.Printf("Peer Connection State has changed: %s\n", s.String())
+	// Everything below is the Pion WebRTC API! Thanks for using it ❤️.
 
-		if s == webrtc.PeerConnectionStateFailed {
-			// Wait until PeerConnection has had no network activity for 30 seconds or another failure. It may be reconnected using an ICE Restart.
-			// Use webrtc.PeerConnectionStateDisconnected if you are interested in detecting faster timeout.
-			// Note that the PeerConnection may come back from PeerConnectionStateDisconnected.
-			fmt.Println("Peer Connection has gone to failed exiting")
-			os.Exit(0)
+	// Every offer process gets its own session and RTCPeerConnection.
+	// The session server answers offers, applies trickled candidates and
+	// drops a session once its PeerConnection has failed or closed.
+	sessions, err := peer.NewSessionServer(cfg, func(id string) []peer.Option {
+		return []peer.Option{
+			// Register data channel creation handling
+			peer.OnDataChannel(func(d *webrtc.DataChannel) {
+				fmt.Printf("Session %s: new DataChannel %s %d\n", id, d.Label(), d.ID())
+
+				// Register channel opening handling
+				d.OnOpen(func() {
+					fmt.Printf("Session %s: data channel '%s'-'%d' open. Random messages will now be sent every 5 seconds\n", id, d.Label(), d.ID())
+
+					ticker := time.NewTicker(5 * time.Second)
+					defer ticker.Stop()
+
+					for range ticker.C {
+						message := signal.RandSeq(15)
+						fmt.Printf("Session %s: sending '%s'\n", id, message)
+
+						// Send the message as text, stop once the session is gone
+						if sendTextErr := d.SendText(message); sendTextErr != nil {
+							fmt.Printf("Session %s: data channel closed: %v\n", id, sendTextErr)
+							return
+						}
+					}
+				})
+
+				// Register text message handling
+				d.OnMessage(func(msg webrtc.DataChannelMessage) {
+					fmt.Printf("Session %s: message from DataChannel '%s': '%s'\n", id, d.Label(), string(msg.Data))
+				})
+			}),
 		}
 	})
+	if err != nil {
+		panic(err)
+	}
 
-	// Register data channel creation handling
-	peerConnection.OnDataChannel(func(d *webrtc.DataChannel) {
-		fmt.Printf("New DataChannel %s %d\n", d.Label(), d.ID())
-
-		// Register channel opening handling
-		d.OnOpen(func() {
-			fmt.Printf("Data channel '%s'-'%d' open. Random messages will now be sent to any connected DataChannels every 5 seconds\n", d.Label(), d.ID())
-
-			for range time.NewTicker(5 * time.Second).C {
-				message := signal.RandSeq(15)
-				fmt.Printf("Sending '%s'\n", message)
-
-				// Send the message as text
-				sendTextErr := d.SendText(message)
-				if sendTextErr != nil {
-					panic(sendTextErr)
-				}
-			}
-		})
-
-		// Register text message handling
-		d.OnMessage(func(msg webrtc.DataChannelMessage) {
-			fmt.Printf("Message from DataChannel '%s': '%s'\n", d.Label(), string(msg.Data))
-		})
-	})
-
-	// Start HTTP server that accepts requests from the offer process to exchange SDP and Candidates
-	panic(http.ListenAndServe(*answerAddr, nil))
+	// Start HTTP server that accepts requests from the offer processes to exchange SDP and Candidates.
+	// Unauthenticated requests are rejected when a config is given, its [tls] table switches to HTTPS.
+	// Oversized bodies get 413, floods of requests or candidates get 429.
+	tlsConfig, err := signaling.ServerTLSConfig(cfg.TLS, cfg.Signaling.ListenAddress)
+	if err != nil {
+		panic(err)
+	}
+	handler := signaling.DefaultLimits.Middleware(signaling.NewAuthenticator(cfg).Middleware(sessions))
+	panic(signaling.ListenAndServe(cfg.Signaling.ListenAddress, handler, tlsConfig))
 }
@@ -1,184 +1,89 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
-	"sync"
 	"time"
 
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/peer"
 	"webrtc-demo/pkg/signal"
+	"webrtc-demo/pkg/signaling"
 
 	"github.com/pion/webrtc/v3"
 )
 
-func signalCandidate(addr string, c *webrtc.ICECandidate) error {
-	payload := []byte(c.ToJSON().Candidate)
-	resp, err := http.Post(fmt.Sprintf("http://%s/candidate", addr), "application/json; charset=utf-8", bytes.NewReader(payload)) //nolint:noctx
-	if err != nil {
-		return err
-	}
-
-	if closeErr := resp.Body.Close(); closeErr != nil {
-		return closeErr
-	}
-
-	return nil
-}
-
 func main() { //nolint:gocognit
-	offerAddr := flag.String("offer-address", ":50000", "Address that the Offer HTTP server is hosted on.")
 	answerAddr := flag.String("answer-address", "127.0.0.1:60000", "Address that the Answer HTTP server is hosted on.")
+	sessionID := flag.String("session", signal.RandSeq(8), "ID of the signaling session, unique per offer process.")
+	configPath := flag.String("config", "", "TOML config whose api_key/api_secret (or token) authenticate signaling, open signaling when empty.")
+	iceFlags := config.NewICEFlags()
 	flag.Parse()
 
-	var candidatesMux sync.Mutex
-	pendingCandidates := make([]*webrtc.ICECandidate, 0)
-
-	// Everything below is the Pion WebRTC API! Thanks for using it ❤️.
-
-	// Prepare the configuration
-	config := webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{
-				URLs: []string{"stun:stun.l.google.com:19302"},
-			},
-		},
-	}
-
-	// Create a new RTCPeerConnection
-	peerConnection, err := webrtc.NewPeerConnection(config)
+	cfg, err := config.GetOptionalConfig(*configPath)
 	if err != nil {
 		panic(err)
 	}
-	defer func() {
-		if cErr := peerConnection.Close(); cErr != nil {
-			fmt.Printf("cannot close peerConnection: %v\n", cErr)
-		}
-	}()
-
-	// When an ICE candidate is available send to the other Pion instance
-	// the other Pion instance will add this candidate by calling AddICECandidate
-	peerConnection.OnICECandidate(func(c *webrtc.ICECandidate) {
-		if c == nil {
-			return
-		}
-
-		candidatesMux.Lock()
-		defer candidatesMux.Unlock()
-
-		desc := peerConnection.RemoteDescription()
-		if desc == nil {
-			pendingCandidates = append(pendingCandidates, c)
-		} else if onICECandidateErr := signalCandidate(*answerAddr, c); onICECandidateErr != nil {
-			panic(onICECandidateErr)
-		}
-	})
-
-	// A HTTP handler that allows the other Pion instance to send us ICE candidates
-	// This allows us to add ICE candidates faster, we don't have to wait for STUN or TURN
-	// candidates which may be slower
-	http.HandleFunc("/candidate", func(w http.ResponseWriter, r *http.Request) {
-		candidate, candidateErr := ioutil.ReadAll(r.Body)
-		if candidateErr != nil {
-			panic(candidateErr)
-		}
-		if candidateErr := peerConnection.AddICECandidate(webrtc.ICECandidateInit{Candidate: string(candidate)}); candidateErr != nil {
-			panic(candidateErr)
-		}
-	})
-
-	// A HTTP handler that processes a SessionDescription given to us from the other Pion process
-	http.HandleFunc("/sdp", func(w http.ResponseWriter, r *http.Request) {
-		sdp := webrtc.SessionDescription{}
-		if sdpErr := json.NewDecoder(r.Body).Decode(&sdp); sdpErr != nil {
-			panic(sdpErr)
-		}
-
-		if sdpErr := peerConnection.SetRemoteDescription(sdp); sdpErr != nil {
-			panic(sdpErr)
-		}
-
-		candidatesMux.Lock()
-		defer candidatesMux.Unlock()
-
-		for _, c := range pendingCandidates {
-			if onICECandidateErr := signalCandidate(*answerAddr, c); onICECandidateErr != nil {
-				panic(onICECandidateErr)
-			}
-		}
-	})
-	// Start HTTP server that accepts requests from the answer process
-	go func() { panic(http.ListenAndServe(*offerAddr, nil)) }()
-
-	// Create a datachannel with label 'data'
-	dataChannel, err := peerConnection.CreateDataChannel("data", nil)
+	// Flags given on the command line win over the config file and the environment
+	config.Override(&cfg, &cfg.Signaling.RemoteAddress, "answer-address", *answerAddr)
+	config.Override(&cfg, &cfg.Signaling.Session, "session", *sessionID)
+	iceFlags.Apply(&cfg)
+	// Every signaling request is signed with the credentials of the config,
+	// and goes over HTTPS when the config has a [tls] table
+	httpClient, err := signaling.NewHTTPClient(cfg)
 	if err != nil {
 		panic(err)
 	}
+	answerURL := signaling.SignalingURL(cfg.TLS, "http", cfg.Signaling.RemoteAddress, "")
 
-	// Set the handler for Peer connection state
-	// This will notify you when the peer has connected/disconnected
-	peerConnection.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
-		fmt.Printf("Peer Connection State has changed: %s\n", s.String())
-
-		if s == webrtc.PeerConnectionStateFailed {
-			// Wait until PeerConnection has had no network activity for 30 seconds or another failure. It may be reconnected using an ICE Restart.
-			// Use webrtc.PeerConnectionStateDisconnected if you are interested in detecting faster timeout.
-			// Note that the PeerConnection may come back from PeerConnectionStateDisconnected.
-			fmt.Println("Peer Connection has gone to failed exiting")
-			os.Exit(0)
-		}
-	})
-
-	// Register channel opening handling
-	dataChannel.OnOpen(func() {
-		fmt.Printf("Data channel '%s'-'%d' open. Random messages will now be sent to any connected DataChannels every 5 seconds\n", dataChannel.Label(), dataChannel.ID())
-
-		for range time.NewTicker(5 * time.Second).C {
-			message := signal.RandSeq(15)
-			fmt.Printf("Sending '%s'\n", message)
-
-			// Send the message as text
-			sendTextErr := dataChannel.SendText(message)
-			if sendTextErr != nil {
-				panic(sendTextErr)
-			}
-		}
-	})
-
-	// Register text message handling
-	dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
-		fmt.Printf("Message from DataChannel '%s': '%s'\n", dataChannel.Label(), string(msg.Data))
-	})
+	// Everything below is the Pion WebRTC API! Thanks for using it ❤️.
 
-	// Create an offer to send to the other process
-	offer, err := peerConnection.CreateOffer(nil)
+	// The offer opens the session on the answer process and reads the answer
+	// and the candidates of the answer process from its responses, so the offer
+	// process does not need to accept connections. Candidates are trickled both
+	// ways and a lost connection is recovered with ICE restarts.
+	fmt.Printf("Signaling session %s\n", cfg.Signaling.Session)
+	session := signaling.DialSession(httpClient, answerURL, cfg.Signaling.Session)
+	p, err := peer.New(peer.Offerer, cfg, signaling.NewConnSignaler(session),
+		// Create a datachannel with label 'data'
+		peer.WithDataChannel("data", func(dataChannel *webrtc.DataChannel) {
+			// Register channel opening handling
+			dataChannel.OnOpen(func() {
+				fmt.Printf("Data channel '%s'-'%d' open. Random messages will now be sent to any connected DataChannels every 5 seconds\n", dataChannel.Label(), dataChannel.ID())
+
+				for range time.NewTicker(5 * time.Second).C {
+					message := signal.RandSeq(15)
+					fmt.Printf("Sending '%s'\n", message)
+
+					// Send the message as text
+					sendTextErr := dataChannel.SendText(message)
+					if sendTextErr != nil {
+						panic(sendTextErr)
+					}
+				}
+			})
+
+			// Register text message handling
+			dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
+				fmt.Printf("Message from DataChannel '%s': '%s'\n", dataChannel.Label(), string(msg.Data))
+			})
+		}),
+		// This will notify you when the peer has connected/disconnected
+		peer.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+			fmt.Printf("Peer Connection State has changed: %s\n", s.String())
+		}),
+	)
 	if err != nil {
 		panic(err)
 	}
+	defer func() {
+		if cErr := p.Close(); cErr != nil {
+			fmt.Printf("cannot close peer: %v\n", cErr)
+		}
+	}()
 
-	// Sets the LocalDescription, and starts our UDP listeners
-	// Note: this will start the gathering of ICE candidates
-	if err = peerConnection.SetLocalDescription(offer); err != nil {
-		panic(err)
+	// Sends the offer and negotiates until the connection is closed or lost for good
+	if err := p.Run(); err != nil {
+		fmt.Printf("Peer Connection ended: %v\n", err)
 	}
-
-	// Send our offer to the HTTP server listening in the other process
-	payload, err := json.Marshal(offer)
-	if err != nil {
-		panic(err)
-	}
-	resp, err := http.Post(fmt.Sprintf("http://%s/sdp", *answerAddr), "application/json; charset=utf-8", bytes.NewReader(payload)) // nolint:noctx
-	if err != nil {
-		panic(err)
-	} else if err := resp.Body.Close(); err != nil {
-		panic(err)
-	}
-
-	// Block forever
-	select {}
 }
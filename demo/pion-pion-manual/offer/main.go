@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signal"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func main() { //nolint:gocognit
+	withQR := flag.Bool("qr", true, "Print every chunk as a terminal QR code too.")
+	chunkSize := flag.Int("chunk-size", signal.DefaultChunkSize, "Characters per armored chunk.")
+	compression := flag.String("compression", string(signal.CompressionZstd), "Compression of the offer: none, gzip, deflate or zstd.")
+	configPath := flag.String("config", "", "TOML config with the ICE servers.")
+	iceFlags := config.NewICEFlags()
+	flag.Parse()
+
+	cfg, err := config.GetOptionalConfig(*configPath)
+	if err != nil {
+		panic(err)
+	}
+	// Flags given on the command line win over the config file and the environment
+	iceFlags.Apply(&cfg)
+
+	c, err := signal.ParseCompression(*compression)
+	if err != nil {
+		panic(err)
+	}
+	signal.DefaultCompression = c
+
+	// Everything below is the Pion WebRTC API! Thanks for using it ❤️.
+
+	// Prepare the configuration
+	config := cfg.WebRTCConfiguration()
+	api, err := cfg.API()
+	if err != nil {
+		panic(err)
+	}
+
+	// Create a new RTCPeerConnection
+	peerConnection, err := api.NewPeerConnection(config)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if cErr := peerConnection.Close(); cErr != nil {
+			fmt.Printf("cannot close peerConnection: %v\n", cErr)
+		}
+	}()
+
+	// Create a datachannel with label 'data'
+	dataChannel, err := peerConnection.CreateDataChannel("data", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// Set the handler for Peer connection state
+	// This will notify you when the peer has connected/disconnected
+	peerConnection.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		fmt.Printf("Peer Connection State has changed: %s\n", s.String())
+
+		if s == webrtc.PeerConnectionStateFailed {
+			// Wait until PeerConnection has had no network activity for 30 seconds or another failure. It may be reconnected using an ICE Restart.
+			// Use webrtc.PeerConnectionStateDisconnected if you are interested in detecting faster timeout.
+			// Note that the PeerConnection may come back from PeerConnectionStateDisconnected.
+			fmt.Println("Peer Connection has gone to failed exiting")
+			os.Exit(0)
+		}
+	})
+
+	// Register channel opening handling
+	dataChannel.OnOpen(func() {
+		fmt.Printf("Data channel '%s'-'%d' open. Random messages will now be sent to any connected DataChannels every 5 seconds\n", dataChannel.Label(), dataChannel.ID())
+
+		for range time.NewTicker(5 * time.Second).C {
+			message := signal.RandSeq(15)
+			fmt.Printf("Sending '%s'\n", message)
+
+			// Send the message as text
+			sendTextErr := dataChannel.SendText(message)
+			if sendTextErr != nil {
+				panic(sendTextErr)
+			}
+		}
+	})
+
+	// Register text message handling
+	dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		fmt.Printf("Message from DataChannel '%s': '%s'\n", dataChannel.Label(), string(msg.Data))
+	})
+
+	// Create an offer to send to the other process
+	offer, err := peerConnection.CreateOffer(nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// There is no trickle by hand, so wait until every candidate is in the offer
+	gatherComplete := webrtc.GatheringCompletePromise(peerConnection)
+	if err = peerConnection.SetLocalDescription(offer); err != nil {
+		panic(err)
+	}
+	<-gatherComplete
+
+	// Show the offer for copying to the answer machine
+	fmt.Println("Paste the offer below into the answer process:")
+	if err = signal.WriteManual(os.Stdout, signal.Encode(*peerConnection.LocalDescription()), *chunkSize, *withQR); err != nil {
+		panic(err)
+	}
+
+	// Wait for the answer to be pasted, all chunks in any order or a single line
+	fmt.Println("Paste the answer of the answer process, then press Enter:")
+	blob, err := signal.NewLineReader(os.Stdin).ReadArmored(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	answer := webrtc.SessionDescription{}
+	signal.Decode(blob, &answer)
+
+	if err = peerConnection.SetRemoteDescription(answer); err != nil {
+		panic(err)
+	}
+
+	// Block forever
+	select {}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signal"
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func main() { //nolint:gocognit
+	redisURL := flag.String("redis-url", "redis://localhost:6379/0", "Redis both processes signal through, rediss:// for TLS.")
+	sessionID := flag.String("session", "demo", "ID of the signaling session, the answer process must use the same.")
+	configPath := flag.String("config", "", "TOML config with the ICE servers and [signaling] redis_url/session, flags win over it.")
+	iceFlags := config.NewICEFlags()
+	flag.Parse()
+
+	cfg, err := config.GetOptionalConfig(*configPath)
+	if err != nil {
+		panic(err)
+	}
+	// Flags given on the command line win over the config file and the environment
+	config.Override(&cfg, &cfg.Signaling.RedisURL, "redis-url", *redisURL)
+	config.Override(&cfg, &cfg.Signaling.Session, "session", *sessionID)
+	iceFlags.Apply(&cfg)
+
+	client, err := signaling.NewRedisClient(cfg.Signaling.RedisURL)
+	if err != nil {
+		panic(err)
+	}
+	defer client.Close()
+
+	// Join the session, offer, answer and candidates all travel over its channels.
+	// Messages wait in Redis until the answer process joins, so it may start later.
+	conn, err := signaling.DialRedis(context.Background(), client, cfg.Signaling.Session, signaling.RoleOffer)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if cErr := conn.Close(); cErr != nil {
+			fmt.Printf("cannot leave signaling session: %v\n", cErr)
+		}
+	}()
+
+	// Everything below is the Pion WebRTC API! Thanks for using it ❤️.
+
+	// Prepare the configuration
+	config := cfg.WebRTCConfiguration()
+	api, err := cfg.API()
+	if err != nil {
+		panic(err)
+	}
+
+	// Create a new RTCPeerConnection
+	peerConnection, err := api.NewPeerConnection(config)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if cErr := peerConnection.Close(); cErr != nil {
+			fmt.Printf("cannot close peerConnection: %v\n", cErr)
+		}
+	}()
+
+	// When an ICE candidate is available publish it to the session.
+	// Candidates are held back until the remote description is set.
+	trickle := signaling.NewTrickle(peerConnection, func(c webrtc.ICECandidateInit) error {
+		return conn.Send(signaling.NewCandidateMessage(c))
+	})
+
+	// Create a datachannel with label 'data'
+	dataChannel, err := peerConnection.CreateDataChannel("data", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// Set the handler for Peer connection state
+	// This will notify you when the peer has connected/disconnected.
+	// A connection that stays disconnected for a few seconds, or fails, is recovered
+	// with ICE restarts over the signaling channel, data channels survive them.
+	restarter := signaling.NewICERestarter(peerConnection, func() error {
+		return trickle.RestartICE(func(offer webrtc.SessionDescription) error {
+			return conn.Send(signaling.NewSDPMessage(offer))
+		})
+	}, signaling.ICERestartOptions{})
+	restarter.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		fmt.Printf("Peer Connection State has changed: %s\n", s.String())
+	})
+	restarter.OnGiveUp(func() {
+		fmt.Println("Peer Connection did not recover exiting")
+		os.Exit(0)
+	})
+
+	// Register channel opening handling
+	dataChannel.OnOpen(func() {
+		fmt.Printf("Data channel '%s'-'%d' open. Random messages will now be sent to any connected DataChannels every 5 seconds\n", dataChannel.Label(), dataChannel.ID())
+
+		for range time.NewTicker(5 * time.Second).C {
+			message := signal.RandSeq(15)
+			fmt.Printf("Sending '%s'\n", message)
+
+			// Send the message as text
+			sendTextErr := dataChannel.SendText(message)
+			if sendTextErr != nil {
+				panic(sendTextErr)
+			}
+		}
+	})
+
+	// Register text message handling
+	dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		fmt.Printf("Message from DataChannel '%s': '%s'\n", dataChannel.Label(), string(msg.Data))
+	})
+
+	// Create an offer to send to the other process
+	offer, err := peerConnection.CreateOffer(nil)
+	if err != nil {
+		panic(err)
+	}
+
+	if err = conn.Send(signaling.NewSDPMessage(offer)); err != nil {
+		panic(err)
+	}
+
+	// Sets the LocalDescription, and starts our UDP listeners
+	// Note: this will start the gathering of ICE candidates
+	if err = trickle.SetLocalDescription(offer); err != nil {
+		panic(err)
+	}
+
+	// Process messages from the answer process until it says bye
+	for {
+		msg, err := conn.Recv()
+		if err != nil {
+			panic(err)
+		}
+
+		switch msg.Type {
+		case signaling.MessageTypeAnswer:
+			if err := trickle.SetRemoteDescription(*msg.SDP); err != nil {
+				panic(err)
+			}
+		case signaling.MessageTypeCandidate:
+			if err := trickle.AddRemoteCandidate(*msg.Candidate); err != nil {
+				panic(err)
+			}
+		case signaling.MessageTypeBye:
+			fmt.Println("Answer process said bye, exiting")
+			return
+		}
+	}
+}
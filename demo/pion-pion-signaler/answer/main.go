@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/iceserver"
+	"webrtc-demo/pkg/signal"
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func main() { //nolint:gocognit
+	transport := flag.String("transport", signaling.TransportHTTP, "Signaling transport: http, websocket, grpc, redis, stdio or file.")
+	listenAddr := flag.String("listen-address", ":60000", "Address the http, websocket and grpc transports receive messages on.")
+	remoteAddr := flag.String("remote-address", "127.0.0.1:50000", "Address of the offer process, for the http transport.")
+	redisURL := flag.String("redis-url", "redis://localhost:6379/0", "Redis of the redis transport.")
+	sessionID := flag.String("session", "demo", "Session ID of the redis and file transports, the offer process must use the same.")
+	dir := flag.String("dir", os.TempDir(), "Directory of the file transport, shared with the offer process.")
+	configPath := flag.String("config", "", "TOML config with the credentials and [tls] table of http, websocket and grpc signaling.")
+	iceFlags := config.NewICEFlags()
+	flag.Parse()
+
+	cfg, err := config.GetOptionalConfig(*configPath)
+	if err != nil {
+		panic(err)
+	}
+	// Flags given on the command line win over the config file and the environment
+	config.Override(&cfg, &cfg.Signaling.Transport, "transport", *transport)
+	config.Override(&cfg, &cfg.Signaling.ListenAddress, "listen-address", *listenAddr)
+	config.Override(&cfg, &cfg.Signaling.RemoteAddress, "remote-address", *remoteAddr)
+	config.Override(&cfg, &cfg.Signaling.RedisURL, "redis-url", *redisURL)
+	config.Override(&cfg, &cfg.Signaling.Session, "session", *sessionID)
+	config.Override(&cfg, &cfg.Signaling.Dir, "dir", *dir)
+	iceFlags.Apply(&cfg)
+
+	// stdout carries the signaling blobs of the stdio transport, so report on stderr then
+	var out io.Writer = os.Stdout
+	if cfg.Signaling.Transport == signaling.TransportStdio {
+		out = os.Stderr
+	}
+
+	// Everything below is transport agnostic, the Signaler hides how messages travel
+	signaler, err := signaling.OpenSignaler(context.Background(), signaling.RoleAnswer, signaling.SignalerOptions{
+		Transport:  cfg.Signaling.Transport,
+		ListenAddr: cfg.Signaling.ListenAddress,
+		RemoteAddr: cfg.Signaling.RemoteAddress,
+		RedisURL:   cfg.Signaling.RedisURL,
+		SessionID:  cfg.Signaling.Session,
+		Dir:        cfg.Signaling.Dir,
+		Config:     cfg,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := signaler.Close(); err != nil {
+			fmt.Fprintf(out, "cannot close signaler: %v\n", err)
+		}
+	}()
+
+	// Host the STUN and TURN servers of the [stun] and [turn] tables of the config,
+	// for peers that have none of their own or sit behind symmetric NAT
+	iceServers, err := iceserver.Start(cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := iceServers.Close(); err != nil {
+			fmt.Fprintf(out, "cannot close ICE servers: %v\n", err)
+		}
+	}()
+
+	// Everything below is the Pion WebRTC API! Thanks for using it ❤️.
+
+	// Prepare the configuration
+	webrtcConfig := cfg.WebRTCConfiguration()
+	api, err := cfg.API()
+	if err != nil {
+		panic(err)
+	}
+
+	// Create a new RTCPeerConnection
+	peerConnection, err := api.NewPeerConnection(webrtcConfig)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := peerConnection.Close(); err != nil {
+			fmt.Fprintf(out, "cannot close peerConnection: %v\n", err)
+		}
+	}()
+
+	// The answer process is the polite peer of perfect negotiation: when both processes
+	// offer at once it drops its own offer and answers the one of the offer process.
+	// Candidates are trickled over the signaler as well.
+	negotiator := signaling.NewNegotiator(peerConnection, signaler, true)
+
+	// Set the handler for Peer connection state
+	// This will notify you when the peer has connected/disconnected.
+	// A connection that stays disconnected for a few seconds, or fails, is recovered
+	// with ICE restarts negotiated over the signaler, data channels survive them.
+	restarter := signaling.NewICERestarter(peerConnection, negotiator.RestartICE, signaling.ICERestartOptions{})
+	restarter.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		fmt.Fprintf(out, "Peer Connection State has changed: %s\n", s.String())
+
+		// Tell the offer process as well, it prints the state it gets
+		if err := signaler.SendState(s); err != nil {
+			fmt.Fprintf(out, "cannot send state: %v\n", err)
+		}
+	})
+	negotiator.OnRemoteState(func(s webrtc.PeerConnectionState) {
+		fmt.Fprintf(out, "Peer Connection State of the offer process: %s\n", s.String())
+	})
+	restarter.OnGiveUp(func() {
+		fmt.Fprintln(out, "Peer Connection did not recover exiting")
+		os.Exit(0)
+	})
+
+	// Register data channel creation handling
+	peerConnection.OnDataChannel(func(d *webrtc.DataChannel) {
+		fmt.Fprintf(out, "New DataChannel %s %d\n", d.Label(), d.ID())
+
+		// Register channel opening handling
+		d.OnOpen(func() {
+			fmt.Fprintf(out, "Data channel '%s'-'%d' open. Random messages will now be sent to any connected DataChannels every 5 seconds\n", d.Label(), d.ID())
+
+			for range time.NewTicker(5 * time.Second).C {
+				message := signal.RandSeq(15)
+				fmt.Fprintf(out, "Sending '%s'\n", message)
+
+				// Send the message as text
+				sendTextErr := d.SendText(message)
+				if sendTextErr != nil {
+					panic(sendTextErr)
+				}
+			}
+		})
+
+		// Register text message handling
+		d.OnMessage(func(msg webrtc.DataChannelMessage) {
+			fmt.Fprintf(out, "Message from DataChannel '%s': '%s'\n", d.Label(), string(msg.Data))
+		})
+	})
+
+	// Either side may negotiate, the answer process opens a data channel of its own.
+	// Its offer usually collides with the first offer of the offer process.
+	greetings, err := peerConnection.CreateDataChannel("greetings", nil)
+	if err != nil {
+		panic(err)
+	}
+	greetings.OnOpen(func() {
+		if sendTextErr := greetings.SendText("hello from the answer process"); sendTextErr != nil {
+			fmt.Fprintf(out, "cannot greet: %v\n", sendTextErr)
+		}
+	})
+
+	// Process messages from the offer process until it says bye
+	if err := negotiator.Run(); err != nil {
+		panic(err)
+	}
+	fmt.Fprintln(out, "Offer process said bye, exiting")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signal"
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func main() { //nolint:gocognit
+	transport := flag.String("transport", signaling.TransportHTTP, "Signaling transport: http, websocket, grpc, redis, stdio or file.")
+	listenAddr := flag.String("listen-address", ":50000", "Address the http transport receives messages on.")
+	remoteAddr := flag.String("remote-address", "127.0.0.1:60000", "Address of the answer process, for the http, websocket and grpc transports.")
+	redisURL := flag.String("redis-url", "redis://localhost:6379/0", "Redis of the redis transport.")
+	sessionID := flag.String("session", "demo", "Session ID of the redis and file transports, the answer process must use the same.")
+	dir := flag.String("dir", os.TempDir(), "Directory of the file transport, shared with the answer process.")
+	configPath := flag.String("config", "", "TOML config with the credentials and [tls] table of http, websocket and grpc signaling.")
+	iceFlags := config.NewICEFlags()
+	flag.Parse()
+
+	cfg, err := config.GetOptionalConfig(*configPath)
+	if err != nil {
+		panic(err)
+	}
+	// Flags given on the command line win over the config file and the environment
+	config.Override(&cfg, &cfg.Signaling.Transport, "transport", *transport)
+	config.Override(&cfg, &cfg.Signaling.ListenAddress, "listen-address", *listenAddr)
+	config.Override(&cfg, &cfg.Signaling.RemoteAddress, "remote-address", *remoteAddr)
+	config.Override(&cfg, &cfg.Signaling.RedisURL, "redis-url", *redisURL)
+	config.Override(&cfg, &cfg.Signaling.Session, "session", *sessionID)
+	config.Override(&cfg, &cfg.Signaling.Dir, "dir", *dir)
+	iceFlags.Apply(&cfg)
+
+	// stdout carries the signaling blobs of the stdio transport, so report on stderr then
+	var out io.Writer = os.Stdout
+	if cfg.Signaling.Transport == signaling.TransportStdio {
+		out = os.Stderr
+	}
+
+	// Everything below is transport agnostic, the Signaler hides how messages travel
+	signaler, err := signaling.OpenSignaler(context.Background(), signaling.RoleOffer, signaling.SignalerOptions{
+		Transport:  cfg.Signaling.Transport,
+		ListenAddr: cfg.Signaling.ListenAddress,
+		RemoteAddr: cfg.Signaling.RemoteAddress,
+		RedisURL:   cfg.Signaling.RedisURL,
+		SessionID:  cfg.Signaling.Session,
+		Dir:        cfg.Signaling.Dir,
+		Config:     cfg,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if cErr := signaler.Close(); cErr != nil {
+			fmt.Fprintf(out, "cannot close signaler: %v\n", cErr)
+		}
+	}()
+
+	// Everything below is the Pion WebRTC API! Thanks for using it ❤️.
+
+	// Prepare the configuration
+	webrtcConfig := cfg.WebRTCConfiguration()
+	api, err := cfg.API()
+	if err != nil {
+		panic(err)
+	}
+
+	// Create a new RTCPeerConnection
+	peerConnection, err := api.NewPeerConnection(webrtcConfig)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if cErr := peerConnection.Close(); cErr != nil {
+			fmt.Fprintf(out, "cannot close peerConnection: %v\n", cErr)
+		}
+	}()
+
+	// The offer process is the impolite peer of perfect negotiation: it offers whenever
+	// OnNegotiationNeeded fires and ignores offers of the answer process that collide.
+	// Candidates are trickled over the signaler as well.
+	negotiator := signaling.NewNegotiator(peerConnection, signaler, false)
+
+	// Create a datachannel with label 'data', this makes the first offer
+	dataChannel, err := peerConnection.CreateDataChannel("data", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// Set the handler for Peer connection state
+	// This will notify you when the peer has connected/disconnected.
+	// A connection that stays disconnected for a few seconds, or fails, is recovered
+	// with ICE restarts negotiated over the signaler, data channels survive them.
+	restarter := signaling.NewICERestarter(peerConnection, negotiator.RestartICE, signaling.ICERestartOptions{})
+	restarter.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		fmt.Fprintf(out, "Peer Connection State has changed: %s\n", s.String())
+
+		// Tell the answer process as well, it prints the state it gets
+		if err := signaler.SendState(s); err != nil {
+			fmt.Fprintf(out, "cannot send state: %v\n", err)
+		}
+	})
+	negotiator.OnRemoteState(func(s webrtc.PeerConnectionState) {
+		fmt.Fprintf(out, "Peer Connection State of the answer process: %s\n", s.String())
+	})
+	restarter.OnGiveUp(func() {
+		fmt.Fprintln(out, "Peer Connection did not recover exiting")
+		os.Exit(0)
+	})
+
+	// Register channel opening handling
+	dataChannel.OnOpen(func() {
+		fmt.Fprintf(out, "Data channel '%s'-'%d' open. Random messages will now be sent to any connected DataChannels every 5 seconds\n", dataChannel.Label(), dataChannel.ID())
+
+		for range time.NewTicker(5 * time.Second).C {
+			message := signal.RandSeq(15)
+			fmt.Fprintf(out, "Sending '%s'\n", message)
+
+			// Send the message as text
+			sendTextErr := dataChannel.SendText(message)
+			if sendTextErr != nil {
+				panic(sendTextErr)
+			}
+		}
+	})
+
+	// Register text message handling
+	dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		fmt.Fprintf(out, "Message from DataChannel '%s': '%s'\n", dataChannel.Label(), string(msg.Data))
+	})
+
+	// Print the messages of data channels the answer process opens
+	peerConnection.OnDataChannel(func(d *webrtc.DataChannel) {
+		d.OnMessage(func(msg webrtc.DataChannelMessage) {
+			fmt.Fprintf(out, "Message from DataChannel '%s': '%s'\n", d.Label(), string(msg.Data))
+		})
+	})
+
+	// Process messages from the answer process until it says bye
+	if err := negotiator.Run(); err != nil {
+		panic(err)
+	}
+	fmt.Fprintln(out, "Answer process said bye, exiting")
+}
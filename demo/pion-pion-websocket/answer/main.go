@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/iceserver"
+	"webrtc-demo/pkg/signal"
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func main() { // nolint:gocognit
+	answerAddr := flag.String("answer-address", ":60000", "Address that the Answer WebSocket server is hosted on.")
+	configPath := flag.String("config", "", "TOML config whose api_key/api_secret (or token) authenticate signaling, open signaling when empty.")
+	iceFlags := config.NewICEFlags()
+	flag.Parse()
+
+	cfg, err := config.GetOptionalConfig(*configPath)
+	if err != nil {
+		panic(err)
+	}
+	// Flags given on the command line win over the config file and the environment
+	config.Override(&cfg, &cfg.Signaling.ListenAddress, "answer-address", *answerAddr)
+	iceFlags.Apply(&cfg)
+
+	tlsConfig, err := signaling.ServerTLSConfig(cfg.TLS, cfg.Signaling.ListenAddress)
+	if err != nil {
+		panic(err)
+	}
+
+	// Wait for the offer process to open the signaling socket,
+	// unauthenticated handshakes are rejected when a config is given
+	conn := <-signaling.StartWebSocketServer(cfg.Signaling.ListenAddress, signaling.NewAuthenticator(cfg), tlsConfig)
+	defer func() {
+		if err := conn.Close(); err != nil {
+			fmt.Printf("cannot close signaling socket: %v\n", err)
+		}
+	}()
+
+	// Host the STUN and TURN servers of the [stun] and [turn] tables of the config,
+	// for peers that have none of their own or sit behind symmetric NAT
+	iceServers, err := iceserver.Start(cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := iceServers.Close(); err != nil {
+			fmt.Printf("cannot close ICE servers: %v\n", err)
+		}
+	}()
+
+	// Everything below is the Pion WebRTC API! Thanks for using it ❤️.
+
+	// Prepare the configuration
+	config := cfg.WebRTCConfiguration()
+	api, err := cfg.API()
+	if err != nil {
+		panic(err)
+	}
+
+	// Create a new RTCPeerConnection
+	peerConnection, err := api.NewPeerConnection(config)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := peerConnection.Close(); err != nil {
+			fmt.Printf("cannot close peerConnection: %v\n", err)
+		}
+	}()
+
+	// When an ICE candidate is available send it over the socket.
+	// Candidates are held back until the remote description is set.
+	trickle := signaling.NewTrickle(peerConnection, func(c webrtc.ICECandidateInit) error {
+		return conn.Send(signaling.NewCandidateMessage(c))
+	})
+
+	// Set the handler for Peer connection state
+	// This will notify you when the peer has connected/disconnected.
+	// A connection that stays disconnected or fails waits for the ICE restarts of the
+	// offer process, the process only exits when they do not reconnect.
+	restarter := signaling.NewICERestarter(peerConnection, nil, signaling.ICERestartOptions{})
+	restarter.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		fmt.Printf("Peer Connection State has changed: %s\n", s.String())
+	})
+	restarter.OnGiveUp(func() {
+		fmt.Println("Peer Connection did not recover exiting")
+		os.Exit(0)
+	})
+
+	// Register data channel creation handling
+	peerConnection.OnDataChannel(func(d *webrtc.DataChannel) {
+		fmt.Printf("New DataChannel %s %d\n", d.Label(), d.ID())
+
+		// Register channel opening handling
+		d.OnOpen(func() {
+			fmt.Printf("Data channel '%s'-'%d' open. Random messages will now be sent to any connected DataChannels every 5 seconds\n", d.Label(), d.ID())
+
+			for range time.NewTicker(5 * time.Second).C {
+				message := signal.RandSeq(15)
+				fmt.Printf("Sending '%s'\n", message)
+
+				// Send the message as text
+				sendTextErr := d.SendText(message)
+				if sendTextErr != nil {
+					panic(sendTextErr)
+				}
+			}
+		})
+
+		// Register text message handling
+		d.OnMessage(func(msg webrtc.DataChannelMessage) {
+			fmt.Printf("Message from DataChannel '%s': '%s'\n", d.Label(), string(msg.Data))
+		})
+	})
+
+	// Process messages from the offer process until it says bye
+	for {
+		msg, err := conn.Recv()
+		if err != nil {
+			panic(err)
+		}
+		// A malformed message of the offer process is no reason to exit
+		if err := msg.Validate(); err != nil {
+			fmt.Println("Ignoring message:", err)
+			continue
+		}
+
+		switch msg.Type {
+		case signaling.MessageTypeOffer:
+			if err := trickle.SetRemoteDescription(*msg.SDP); err != nil {
+				panic(err)
+			}
+
+			// Create an answer to send to the other process
+			answer, err := peerConnection.CreateAnswer(nil)
+			if err != nil {
+				panic(err)
+			}
+
+			if err := conn.Send(signaling.NewSDPMessage(answer)); err != nil {
+				panic(err)
+			}
+
+			// Sets the LocalDescription, and starts our UDP listeners
+			if err := trickle.SetLocalDescription(answer); err != nil {
+				panic(err)
+			}
+		case signaling.MessageTypeCandidate:
+			if err := trickle.AddRemoteCandidate(*msg.Candidate); err != nil {
+				panic(err)
+			}
+		case signaling.MessageTypeBye:
+			fmt.Println("Offer process said bye, exiting")
+			return
+		}
+	}
+}
@@ -1,20 +1,405 @@
 package config
 
-import "github.com/BurntSushi/toml"
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 
+	"github.com/BurntSushi/toml"
+	"github.com/pion/webrtc/v3"
+)
+
+// EnvPrefix starts the environment variables that override a config file
+const EnvPrefix = "WEBRTC_DEMO_"
+
+// DefaultFFmpegCmd encodes the demo video to the H264 Annex B stream the livekit offer reads from stdout
+const DefaultFFmpegCmd = "ffmpeg -rtbufsize 100M -i ./media/never_gonna_give_you_up.mp4 -pix_fmt yuv420p -c:v libx264 -bsf:v h264_mp4toannexb -b:v 2M -max_delay 0 -bf 0 -f h264 -"
+
+// The LiveKit dev server of demo/pion-pion-livekit/livekit.yaml, whose room Default joins.
+// Its key pair is public, so it does not authenticate signaling, see Authenticated.
+const (
+	DevLiveKitHost     = "ws://localhost:7880"
+	DevLiveKitRoom     = "stark-tower"
+	DevAPIKey          = "APInAy27RUmYUnV"
+	DevAPISecret       = "90jQt67cwele8a6uIuIQLK0ZJ0cJKXnzz6iEI8h43dO"
+	devLiveKitIdentity = "get-sdp"
+)
+
+// Config is the runtime configuration shared by every program: LiveKit
+// settings, TLS, ICE servers, signaling endpoints, media sources and codecs.
+//
+// Every value is taken from the first of these that sets it:
+//
+//  1. flags given on the command line, see Override
+//  2. environment variables, EnvPrefix and the TOML key in upper case with
+//     tables joined by _, e.g. WEBRTC_DEMO_SIGNALING_LISTEN_ADDRESS
+//  3. the TOML file given with --config
+//  4. Default
+//  5. the defaults of the flags of the program, for the fields Default leaves empty
+//     and neither file nor environment set, not even to a zero value
 type Config struct {
+	// LiveKit server and room, api_key/api_secret and token also authenticate signaling
 	Host      string `toml:"host"`
 	ApiKey    string `toml:"api_key"`
 	ApiSecret string `toml:"api_secret"`
 	Identity  string `toml:"identity"`
 	Token     string `toml:"token"`
 	RoomName  string `toml:"room_name"`
+
+	TLS TLSConfig `toml:"tls"`
+
+	// ICEServers are the STUN and TURN servers of every PeerConnection, none by
+	// default so only host candidates are gathered and nothing depends on the
+	// internet. WEBRTC_DEMO_ICE_SERVERS takes comma separated URLs, one server each.
+	ICEServers []ICEServer `toml:"ice_servers"`
+	// ICETransportPolicy is "all", the default, or "relay" to connect through TURN only
+	ICETransportPolicy string `toml:"ice_transport_policy"`
+	// ICE tunes how candidates are gathered, see SettingEngine
+	ICE ICEConfig `toml:"ice"`
+
+	// STUN and TURN start embedded servers in the answering process
+	STUN STUNConfig `toml:"stun"`
+	TURN TURNConfig `toml:"turn"`
+
+	Signaling SignalingConfig `toml:"signaling"`
+	Media     MediaConfig     `toml:"media"`
+
+	// defined holds the keys the file or the environment set, like "signaling.url"
+	defined map[string]bool
+}
+
+// TLSConfig is the [tls] table of a config, it switches signaling to https:// and wss://.
+//
+// Servers use CertFile/KeyFile, or a certificate generated in memory when SelfSigned is set.
+// Clients trust the system roots, the CAs in CAFile, or only the certificate
+// whose SHA-256 Fingerprint is pinned.
+type TLSConfig struct {
+	CertFile    string `toml:"cert_file"`
+	KeyFile     string `toml:"key_file"`
+	SelfSigned  bool   `toml:"self_signed"`
+	CAFile      string `toml:"ca_file"`
+	Fingerprint string `toml:"fingerprint"`
+}
+
+// Enabled reports whether signaling runs over TLS
+func (c TLSConfig) Enabled() bool {
+	return c != TLSConfig{}
+}
+
+// ICEServer is an [[ice_servers]] entry, a STUN or TURN server.
+//
+// TURN servers take a Username and a Credential, which is a password unless
+// CredentialType is "oauth": then it is the access token and MACKey its key.
+type ICEServer struct {
+	URLs           []string `toml:"urls"`
+	Username       string   `toml:"username"`
+	Credential     string   `toml:"credential"`
+	CredentialType string   `toml:"credential_type"`
+	MACKey         string   `toml:"mac_key"`
 }
 
+// STUNConfig is the [stun] table, the embedded STUN server an answering
+// process starts when ListenAddress is set
+type STUNConfig struct {
+	// ListenAddress takes STUN over UDP, e.g. ":3478"
+	ListenAddress string `toml:"listen_address"`
+}
+
+// Enabled reports whether the embedded STUN server runs
+func (c STUNConfig) Enabled() bool {
+	return c.ListenAddress != ""
+}
+
+// TURNConfig is the [turn] table, the embedded TURN server an answering
+// process starts when ListenAddress is set. It answers STUN as well.
+type TURNConfig struct {
+	// ListenAddress takes TURN over UDP and TCP, e.g. ":3478"
+	ListenAddress string `toml:"listen_address"`
+	// PublicIP is the address of the relayed candidates, 127.0.0.1 on loopback
+	PublicIP string `toml:"public_ip"`
+	// Realm, Username and Password are the long-term credentials of the only user
+	Realm    string `toml:"realm"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+}
+
+// Enabled reports whether the embedded TURN server runs
+func (c TURNConfig) Enabled() bool {
+	return c.ListenAddress != ""
+}
+
+// SignalingConfig is the [signaling] table, where a program serves signaling
+// and where it finds its remote peer. Each program reads the fields it needs.
+type SignalingConfig struct {
+	// ListenAddress is served by this process, e.g. ":60000"
+	ListenAddress string `toml:"listen_address"`
+	// RemoteAddress is the ListenAddress of the remote peer, e.g. "127.0.0.1:60000"
+	RemoteAddress string `toml:"remote_address"`
+	// URL is the WHIP or WHEP endpoint of the publisher and subscriber
+	URL string `toml:"url"`
+	// Transport, Session, RedisURL and Dir pick the transport of the signaler and redis demos
+	Transport string `toml:"transport"`
+	Session   string `toml:"session"`
+	RedisURL  string `toml:"redis_url"`
+	Dir       string `toml:"dir"`
+}
+
+// MediaConfig is the [media] table, what is sent and received
+type MediaConfig struct {
+	// FFmpegCmd writes the H264 Annex B stream of the livekit offer to stdout
+	FFmpegCmd string `toml:"ffmpeg_cmd"`
+	// VideoAddress is the UDP address the publisher reads H264 Annex B from
+	VideoAddress string `toml:"video_address"`
+	// VideoSink and AudioSink are where the subscriber writes what it receives
+	VideoSink string `toml:"video_sink"`
+	AudioSink string `toml:"audio_sink"`
+
+	// VideoCodec is the MIME type of published and relayed video, e.g. video/H264
+	VideoCodec string `toml:"video_codec"`
+	// VideoWidth and VideoHeight are announced for the track published to LiveKit
+	VideoWidth  int `toml:"video_width"`
+	VideoHeight int `toml:"video_height"`
+}
+
+// VideoCapability is the codec of the video tracks a program creates
+func (m MediaConfig) VideoCapability() webrtc.RTPCodecCapability {
+	return webrtc.RTPCodecCapability{MimeType: m.VideoCodec}
+}
+
+// Default is what a program runs with when neither file, environment nor flags set a value
+func Default() Config {
+	return Config{
+		Host:       DevLiveKitHost,
+		ApiKey:     DevAPIKey,
+		ApiSecret:  DevAPISecret,
+		Identity:   devLiveKitIdentity,
+		RoomName:   DevLiveKitRoom,
+		ICEServers: []ICEServer{},
+		Media: MediaConfig{
+			FFmpegCmd:   DefaultFFmpegCmd,
+			VideoCodec:  webrtc.MimeTypeH264,
+			VideoWidth:  1920,
+			VideoHeight: 1080,
+		},
+	}
+}
+
+// Authenticated reports whether c has an api key to sign and verify signaling with,
+// the public key pair of the LiveKit dev server does not count, nor does its secret
+func (c Config) Authenticated() bool {
+	return c.ApiKey != "" && c.ApiSecret != "" && c.ApiKey != DevAPIKey && c.ApiSecret != DevAPISecret
+}
+
+// WebRTCConfiguration is the configuration of the PeerConnections of a program
+func (c Config) WebRTCConfiguration() webrtc.Configuration {
+	iceServers := []webrtc.ICEServer{}
+	for _, s := range c.ICEServers {
+		server := webrtc.ICEServer{URLs: s.URLs}
+		switch {
+		case s.CredentialType == credentialTypeOauth:
+			server.Username = s.Username
+			server.Credential = webrtc.OAuthCredential{MACKey: s.MACKey, AccessToken: s.Credential}
+			server.CredentialType = webrtc.ICECredentialTypeOauth
+		case s.Username != "" || s.Credential != "":
+			server.Username = s.Username
+			server.Credential = s.Credential
+			server.CredentialType = webrtc.ICECredentialTypePassword
+		}
+		iceServers = append(iceServers, server)
+	}
+
+	policy := webrtc.ICETransportPolicyAll
+	if c.ICETransportPolicy == transportPolicyRelay {
+		policy = webrtc.ICETransportPolicyRelay
+	}
+
+	return webrtc.Configuration{ICEServers: iceServers, ICETransportPolicy: policy}
+}
+
+// Values of ICEServer.CredentialType and Config.ICETransportPolicy
+const (
+	credentialTypePassword = "password"
+	credentialTypeOauth    = "oauth"
+	transportPolicyAll     = "all"
+	transportPolicyRelay   = "relay"
+)
+
+// validate rejects values WebRTCConfiguration does not know
+func (c Config) validate() error {
+	for _, s := range c.ICEServers {
+		switch s.CredentialType {
+		case "", credentialTypePassword, credentialTypeOauth:
+		default:
+			return fmt.Errorf("ice_servers: unknown credential_type %q, want password or oauth", s.CredentialType)
+		}
+		for _, url := range s.URLs {
+			if isTURN(url) && (s.Username == "" || s.Credential == "") {
+				return fmt.Errorf("ice_servers: %s needs a username and a credential", url)
+			}
+		}
+	}
+	if c.ApiKey != "" && c.ApiKey != DevAPIKey && (c.ApiSecret == "" || c.ApiSecret == DevAPISecret) {
+		return fmt.Errorf("api_key %s needs its own api_secret, the one of the dev server is public", c.ApiKey)
+	}
+	switch c.ICETransportPolicy {
+	case "", transportPolicyAll, transportPolicyRelay:
+	default:
+		return fmt.Errorf("unknown ice_transport_policy %q, want all or relay", c.ICETransportPolicy)
+	}
+	if c.TURN.Enabled() && c.TURN.PublicIP == "" {
+		return fmt.Errorf("turn: public_ip is required with listen_address")
+	}
+	if c.TURN.Enabled() && (c.TURN.Username == "" || c.TURN.Password == "") {
+		return fmt.Errorf("turn: username and password are required with listen_address")
+	}
+	_, err := c.SettingEngine()
+	return err
+}
+
+// isTURN reports whether url is a turn: or turns: URL
+func isTURN(url string) bool {
+	return strings.HasPrefix(url, "turn:") || strings.HasPrefix(url, "turns:")
+}
+
+// GetConfig loads the TOML file at path over Default and applies the environment
 func GetConfig(path string) (Config, error) {
-	cfg := Config{}
-	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+	cfg := Default()
+	cfg.defined = map[string]bool{}
+	meta, err := toml.DecodeFile(path, &cfg)
+	if err != nil {
+		return cfg, err
+	}
+	for _, key := range meta.Keys() {
+		cfg.defined[key.String()] = true
+	}
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem(), EnvPrefix, "", cfg.defined); err != nil {
 		return cfg, err
 	}
-	return cfg, nil
+	return cfg, cfg.validate()
+}
+
+// GetOptionalConfig is GetConfig that skips the file when path is empty
+func GetOptionalConfig(path string) (Config, error) {
+	if path == "" {
+		cfg := Default()
+		cfg.defined = map[string]bool{}
+		if err := applyEnv(reflect.ValueOf(&cfg).Elem(), EnvPrefix, "", cfg.defined); err != nil {
+			return cfg, err
+		}
+		return cfg, cfg.validate()
+	}
+	return GetConfig(path)
+}
+
+// Override applies the flag name of the command line to field of cfg, a loaded Config:
+// value wins when the flag was given, and fills the field when file and environment
+// did not set it and Default left it empty, so the flag default comes last.
+func Override[T comparable](cfg *Config, field *T, name string, value T) {
+	var zero T
+	if flagGiven(name) || (!cfg.defined[cfg.keyOf(field)] && *field == zero) {
+		*field = value
+	}
+}
+
+// keyOf returns the TOML key of a field of c, like "signaling.url", field points into c
+func (c *Config) keyOf(field any) string {
+	return keyOf(reflect.ValueOf(c).Elem(), reflect.ValueOf(field), "")
+}
+
+func keyOf(v, field reflect.Value, table string) string {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("toml")
+		if tag == "" {
+			continue
+		}
+		key := table + tag
+		f := v.Field(i)
+
+		// A table starts at the address of its first field, the types tell them apart
+		if f.Addr().Pointer() == field.Pointer() && f.Addr().Type() == field.Type() {
+			return key
+		}
+		if f.Kind() == reflect.Struct {
+			if k := keyOf(f, field, key+"."); k != "" {
+				return k
+			}
+		}
+	}
+	return ""
+}
+
+// flagGiven reports whether the flag name was given on the command line
+func flagGiven(name string) bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
+}
+
+// applyEnv sets the fields of the struct v from environment variables named
+// prefix and their TOML key, nested tables extend the prefix and table. The
+// keys it sets are added to defined.
+func applyEnv(v reflect.Value, prefix, table string, defined map[string]bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("toml")
+		if key == "" {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, name+"_", table+key+".", defined); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		defined[table+key] = true
+
+		switch field.Interface().(type) {
+		case string:
+			field.SetString(value)
+		case int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			field.SetInt(int64(n))
+		case bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			field.SetBool(b)
+		case []string:
+			field.Set(reflect.ValueOf(splitList(value)))
+		case []ICEServer:
+			field.Set(reflect.ValueOf(ParseICEServers(value)))
+		}
+	}
+	return nil
+}
+
+// ParseICEServers turns comma separated URLs into one ICEServer each, an empty string into none
+func ParseICEServers(urls string) []ICEServer {
+	servers := []ICEServer{}
+	for _, url := range strings.Split(urls, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			servers = append(servers, ICEServer{URLs: []string{url}})
+		}
+	}
+	return servers
 }
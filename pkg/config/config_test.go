@@ -0,0 +1,172 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadTOML loads a config file holding content
+func loadTOML(t *testing.T, content string) (Config, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return GetConfig(path)
+}
+
+func TestTURNCredentialsRequired(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{
+			name:    "embedded server without password",
+			content: "[turn]\nlisten_address = \":3478\"\npublic_ip = \"127.0.0.1\"\nusername = \"demo\"\n",
+			want:    "turn: username and password",
+		},
+		{
+			name:    "embedded server without username",
+			content: "[turn]\nlisten_address = \":3478\"\npublic_ip = \"127.0.0.1\"\npassword = \"demo\"\n",
+			want:    "turn: username and password",
+		},
+		{
+			name:    "ice server without credential",
+			content: "[[ice_servers]]\nurls = [\"stun:127.0.0.1:3478\", \"turn:127.0.0.1:3478\"]\nusername = \"demo\"\n",
+			want:    "turn:127.0.0.1:3478 needs a username and a credential",
+		},
+		{
+			name:    "turns server without username",
+			content: "[[ice_servers]]\nurls = [\"turns:127.0.0.1:5349\"]\ncredential = \"demo\"\n",
+			want:    "turns:127.0.0.1:5349 needs a username and a credential",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadTOML(t, test.content)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got %v, want an error with %q", err, test.want)
+			}
+		})
+	}
+
+	// STUN needs no credentials, TURN with both loads
+	if _, err := loadTOML(t, "[[ice_servers]]\nurls = [\"stun:127.0.0.1:3478\"]\n\n[[ice_servers]]\nurls = [\"turn:127.0.0.1:3478\"]\nusername = \"demo\"\ncredential = \"demo\"\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// parseFlags replaces the command line of Override with a video-width flag parsed from args
+func parseFlags(t *testing.T, args ...string) int {
+	t.Helper()
+
+	commandLine := flag.CommandLine
+	t.Cleanup(func() { flag.CommandLine = commandLine })
+
+	flag.CommandLine = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	videoWidth := flag.Int("video-width", 640, "")
+	if err := flag.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return *videoWidth
+}
+
+func TestOverridePrecedence(t *testing.T) {
+	t.Setenv(EnvPrefix+"SIGNALING_REMOTE_ADDRESS", "127.0.0.1:7000")
+	cfg, err := loadTOML(t, "[signaling]\nremote_address = \"127.0.0.1:6000\"\nlisten_address = \":6000\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	videoWidth := parseFlags(t)
+	Override(&cfg, &cfg.Media.VideoWidth, "video-width", videoWidth)
+	Override(&cfg, &cfg.Signaling.RemoteAddress, "offer-address", "localhost:50000")
+	Override(&cfg, &cfg.Signaling.ListenAddress, "answer-address", ":60000")
+	Override(&cfg, &cfg.Signaling.URL, "whip-url", "http://localhost:8080/whip")
+
+	want := Config{}
+	want.Media.VideoWidth = 1920                      // Default over the flag default
+	want.Signaling.RemoteAddress = "127.0.0.1:7000"   // environment over the file
+	want.Signaling.ListenAddress = ":6000"            // file over the flag default
+	want.Signaling.URL = "http://localhost:8080/whip" // flag default where Default is empty
+	if cfg.Media.VideoWidth != want.Media.VideoWidth || cfg.Signaling != want.Signaling {
+		t.Fatalf("got width %d and %+v, want width %d and %+v", cfg.Media.VideoWidth, cfg.Signaling, want.Media.VideoWidth, want.Signaling)
+	}
+
+	// A flag given on the command line wins over everything
+	videoWidth = parseFlags(t, "--video-width", "800")
+	Override(&cfg, &cfg.Media.VideoWidth, "video-width", videoWidth)
+	if cfg.Media.VideoWidth != 800 {
+		t.Fatalf("got width %d, want the flag 800", cfg.Media.VideoWidth)
+	}
+}
+
+func TestDefaultJoinsDevRoom(t *testing.T) {
+	cfg, err := loadTOML(t, "room_name = \"avengers\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != DevLiveKitHost || cfg.ApiKey != DevAPIKey || cfg.RoomName != "avengers" {
+		t.Fatalf("got host %q, key %q and room %q, want the dev server with room avengers", cfg.Host, cfg.ApiKey, cfg.RoomName)
+	}
+	if cfg.Authenticated() {
+		t.Fatal("the dev key pair authenticates signaling")
+	}
+
+	cfg.ApiKey, cfg.ApiSecret = "key", "secret"
+	if !cfg.Authenticated() {
+		t.Fatal("an own key pair does not authenticate signaling")
+	}
+}
+
+func TestOwnKeyNeedsOwnSecret(t *testing.T) {
+	for _, content := range []string{
+		"api_key = \"key\"\n",
+		"api_key = \"key\"\napi_secret = \"" + DevAPISecret + "\"\n",
+	} {
+		if _, err := loadTOML(t, content); err == nil || !strings.Contains(err.Error(), "api_secret") {
+			t.Fatalf("%q: got %v, want an error about api_secret", content, err)
+		}
+	}
+
+	t.Setenv(EnvPrefix+"API_KEY", "key")
+	if _, err := GetOptionalConfig(""); err == nil {
+		t.Fatal("an api key from the environment signs with the dev secret")
+	}
+	t.Setenv(EnvPrefix+"API_SECRET", "secret")
+	cfg, err := GetOptionalConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Authenticated() {
+		t.Fatal("an own key pair does not authenticate signaling")
+	}
+}
+
+func TestOverrideKeepsExplicitZero(t *testing.T) {
+	t.Setenv(EnvPrefix+"SIGNALING_URL", "")
+	cfg, err := loadTOML(t, "[media]\nvideo_width = 0\n\n[ice]\nlite = false\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Override(&cfg, &cfg.Media.VideoWidth, "video-width", parseFlags(t))
+	Override(&cfg, &cfg.Signaling.URL, "whip-url", "http://localhost:8080/whip")
+	Override(&cfg, &cfg.Media.VideoHeight, "video-height", 480)
+	if cfg.Media.VideoWidth != 0 || cfg.Signaling.URL != "" {
+		t.Fatalf("got width %d and url %q, want the zero values of file and environment", cfg.Media.VideoWidth, cfg.Signaling.URL)
+	}
+	if cfg.Media.VideoHeight != 1080 {
+		t.Fatalf("got height %d, want 1080 of Default", cfg.Media.VideoHeight)
+	}
+
+	if key := cfg.keyOf(&cfg.ICE.Lite); key != "ice.lite" || !cfg.defined[key] {
+		t.Fatalf("got key %q, want ice.lite set by the file", key)
+	}
+	if key := cfg.keyOf(&cfg.ICE.PortMin); key != "ice.port_min" || cfg.defined[key] {
+		t.Fatalf("got key %q, want ice.port_min left unset", key)
+	}
+}
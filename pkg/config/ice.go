@@ -0,0 +1,285 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/ice/v2"
+	"github.com/pion/interceptor"
+	"github.com/pion/webrtc/v3"
+)
+
+// tcpMuxReadBufferSize is how many packets the ICE-TCP listener buffers for each connection
+const tcpMuxReadBufferSize = 8
+
+// Defaults of the ICE agent of Pion, SetICETimeouts takes all three at once
+const (
+	defaultDisconnectedTimeout = 5 * time.Second
+	defaultFailedTimeout       = 25 * time.Second
+	defaultKeepaliveInterval   = 2 * time.Second
+)
+
+// ICEConfig is the [ice] table, how the ICE agents of a program gather
+// candidates. Zero fields keep the defaults of Pion.
+type ICEConfig struct {
+	// PortMin and PortMax bound the local UDP ports of candidates, e.g. for firewall rules
+	PortMin int `toml:"port_min"`
+	PortMax int `toml:"port_max"`
+	// NAT1To1IPs are the external IPs of a 1:1 NAT, e.g. the host of a Docker
+	// container, advertised as NAT1To1CandidateType "host", the default, or "srflx"
+	NAT1To1IPs           []string `toml:"nat_1to1_ips"`
+	NAT1To1CandidateType string   `toml:"nat_1to1_candidate_type"`
+	// NetworkTypes are some of udp4, udp6, tcp4 and tcp6. Pion gathers udp4 and
+	// udp6 by default, all four with a TCPListenAddress.
+	NetworkTypes []string `toml:"network_types"`
+	// Interfaces gathers on the named network interfaces only, ExcludeInterfaces never on them
+	Interfaces        []string `toml:"interfaces"`
+	ExcludeInterfaces []string `toml:"exclude_interfaces"`
+	// MDNS is "disabled", "query-only", the default, or "query-and-gather" to hide host IPs behind .local names
+	MDNS string `toml:"mdns"`
+	// DisconnectedTimeout, FailedTimeout and KeepaliveInterval are durations like "5s"
+	DisconnectedTimeout string `toml:"disconnected_timeout"`
+	FailedTimeout       string `toml:"failed_timeout"`
+	KeepaliveInterval   string `toml:"keepalive_interval"`
+
+	// TCPListenAddress is the single port of passive ICE-TCP candidates, e.g. ":8443",
+	// every PeerConnection of the program shares it. Meant for the answer side.
+	TCPListenAddress string `toml:"tcp_listen_address"`
+	// Lite runs ICE-lite, host candidates only and no connectivity checks of its own,
+	// for an answer side on a public server. The offer side must not be lite.
+	Lite bool `toml:"lite"`
+}
+
+// mdnsModes are the values of ICEConfig.MDNS
+var mdnsModes = map[string]ice.MulticastDNSMode{
+	"disabled":         ice.MulticastDNSModeDisabled,
+	"query-only":       ice.MulticastDNSModeQueryOnly,
+	"query-and-gather": ice.MulticastDNSModeQueryAndGather,
+}
+
+// SettingEngine applies the [ice] table of the config
+func (c Config) SettingEngine() (webrtc.SettingEngine, error) {
+	s := webrtc.SettingEngine{}
+	iceConfig := c.ICE
+
+	if iceConfig.PortMin != 0 || iceConfig.PortMax != 0 {
+		if iceConfig.PortMin < 1 || iceConfig.PortMax > 65535 {
+			return s, fmt.Errorf("ice: port range %d-%d is out of 1-65535", iceConfig.PortMin, iceConfig.PortMax)
+		}
+		if err := s.SetEphemeralUDPPortRange(uint16(iceConfig.PortMin), uint16(iceConfig.PortMax)); err != nil {
+			return s, fmt.Errorf("ice: port range %d-%d: %w", iceConfig.PortMin, iceConfig.PortMax, err)
+		}
+	}
+
+	if len(iceConfig.NAT1To1IPs) > 0 {
+		candidateType := webrtc.ICECandidateTypeHost
+		if iceConfig.NAT1To1CandidateType != "" {
+			t, err := webrtc.NewICECandidateType(iceConfig.NAT1To1CandidateType)
+			if err != nil || (t != webrtc.ICECandidateTypeHost && t != webrtc.ICECandidateTypeSrflx) {
+				return s, fmt.Errorf("ice: nat_1to1_candidate_type %q, want host or srflx", iceConfig.NAT1To1CandidateType)
+			}
+			candidateType = t
+		}
+		s.SetNAT1To1IPs(iceConfig.NAT1To1IPs, candidateType)
+	}
+
+	if len(iceConfig.NetworkTypes) > 0 {
+		networkTypes := []webrtc.NetworkType{}
+		for _, raw := range iceConfig.NetworkTypes {
+			t, err := webrtc.NewNetworkType(raw)
+			if err != nil {
+				return s, fmt.Errorf("ice: network_types: %w", err)
+			}
+			networkTypes = append(networkTypes, t)
+		}
+		s.SetNetworkTypes(networkTypes)
+	}
+
+	if len(iceConfig.Interfaces) > 0 || len(iceConfig.ExcludeInterfaces) > 0 {
+		s.SetInterfaceFilter(func(name string) bool {
+			return (len(iceConfig.Interfaces) == 0 || slices.Contains(iceConfig.Interfaces, name)) && !slices.Contains(iceConfig.ExcludeInterfaces, name)
+		})
+	}
+
+	if iceConfig.MDNS != "" {
+		mode, ok := mdnsModes[iceConfig.MDNS]
+		if !ok {
+			return s, fmt.Errorf("ice: mdns %q, want disabled, query-only or query-and-gather", iceConfig.MDNS)
+		}
+		s.SetICEMulticastDNSMode(mode)
+	}
+
+	if iceConfig.DisconnectedTimeout != "" || iceConfig.FailedTimeout != "" || iceConfig.KeepaliveInterval != "" {
+		disconnected, err := parseDuration("disconnected_timeout", iceConfig.DisconnectedTimeout, defaultDisconnectedTimeout)
+		if err != nil {
+			return s, err
+		}
+		failed, err := parseDuration("failed_timeout", iceConfig.FailedTimeout, defaultFailedTimeout)
+		if err != nil {
+			return s, err
+		}
+		keepalive, err := parseDuration("keepalive_interval", iceConfig.KeepaliveInterval, defaultKeepaliveInterval)
+		if err != nil {
+			return s, err
+		}
+		s.SetICETimeouts(disconnected, failed, keepalive)
+	}
+
+	if iceConfig.Lite {
+		if iceConfig.NAT1To1CandidateType == "srflx" {
+			return s, fmt.Errorf("ice: lite gathers host candidates only, nat_1to1_candidate_type must be host")
+		}
+		s.SetLite(true)
+	}
+
+	return s, nil
+}
+
+// allNetworkTypes are the default NetworkTypes with a TCPListenAddress, Pion leaves out TCP
+var allNetworkTypes = []webrtc.NetworkType{
+	webrtc.NetworkTypeUDP4, webrtc.NetworkTypeUDP6, webrtc.NetworkTypeTCP4, webrtc.NetworkTypeTCP6,
+}
+
+// tcpMuxes are the ICE-TCP listeners of the program by address, API shares them
+var (
+	tcpMuxesMu sync.Mutex
+	tcpMuxes   = map[string]ice.TCPMux{}
+)
+
+// API creates the PeerConnections of a program: the default codecs and
+// interceptors of webrtc.NewPeerConnection, with the SettingEngine of the config.
+// It listens on the TCPListenAddress of the [ice] table once, for the lifetime
+// of the program, every API of that address shares the listener and gathers
+// TCP candidates on it unless NetworkTypes leaves them out.
+func (c Config) API() (*webrtc.API, error) {
+	settingEngine, err := c.SettingEngine()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ICE.TCPListenAddress != "" {
+		mux, err := tcpMux(c.ICE.TCPListenAddress)
+		if err != nil {
+			return nil, err
+		}
+		settingEngine.SetICETCPMux(mux)
+		if len(c.ICE.NetworkTypes) == 0 {
+			settingEngine.SetNetworkTypes(allNetworkTypes)
+		}
+	}
+
+	m := &webrtc.MediaEngine{}
+	if err := m.RegisterDefaultCodecs(); err != nil {
+		return nil, err
+	}
+	i := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(m, i); err != nil {
+		return nil, err
+	}
+
+	return webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithInterceptorRegistry(i), webrtc.WithSettingEngine(settingEngine)), nil
+}
+
+// tcpMux returns the ICE-TCP listener on address, it listens on the first call
+func tcpMux(address string) (ice.TCPMux, error) {
+	tcpMuxesMu.Lock()
+	defer tcpMuxesMu.Unlock()
+
+	if mux, ok := tcpMuxes[address]; ok {
+		return mux, nil
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("ice: tcp_listen_address: %w", err)
+	}
+	mux := webrtc.NewICETCPMux(nil, listener, tcpMuxReadBufferSize)
+	tcpMuxes[address] = mux
+	return mux, nil
+}
+
+// ICEFlags are the --ice-* flags of a program, one for each field of the [ice] table
+type ICEFlags struct {
+	portMin              *int
+	portMax              *int
+	nat1To1IPs           *string
+	nat1To1CandidateType *string
+	networkTypes         *string
+	interfaces           *string
+	excludeInterfaces    *string
+	mdns                 *string
+	disconnectedTimeout  *string
+	failedTimeout        *string
+	keepaliveInterval    *string
+	tcpListenAddress     *string
+	lite                 *bool
+}
+
+// NewICEFlags defines the --ice-* flags on the command line, call it before flag.Parse
+func NewICEFlags() *ICEFlags {
+	return &ICEFlags{
+		portMin:              flag.Int("ice-port-min", 0, "Lowest local UDP port of ICE candidates."),
+		portMax:              flag.Int("ice-port-max", 0, "Highest local UDP port of ICE candidates."),
+		nat1To1IPs:           flag.String("ice-nat-1to1-ips", "", "Comma separated external IPs of a 1:1 NAT, e.g. the Docker host."),
+		nat1To1CandidateType: flag.String("ice-nat-1to1-candidate-type", "", "Candidate type of the 1:1 NAT IPs, host or srflx."),
+		networkTypes:         flag.String("ice-network-types", "", "Comma separated network types to gather, of udp4, udp6, tcp4 and tcp6."),
+		interfaces:           flag.String("ice-interfaces", "", "Comma separated network interfaces to gather on, all when empty."),
+		excludeInterfaces:    flag.String("ice-exclude-interfaces", "", "Comma separated network interfaces never to gather on."),
+		mdns:                 flag.String("ice-mdns", "", "mDNS mode: disabled, query-only or query-and-gather."),
+		disconnectedTimeout:  flag.String("ice-disconnected-timeout", "", "Time without traffic before ICE is disconnected, e.g. 5s."),
+		failedTimeout:        flag.String("ice-failed-timeout", "", "Time disconnected before ICE has failed, e.g. 25s."),
+		keepaliveInterval:    flag.String("ice-keepalive-interval", "", "Interval of ICE keepalives, e.g. 2s."),
+		tcpListenAddress:     flag.String("ice-tcp-listen-address", "", "Single TCP port of passive ICE-TCP candidates, e.g. :8443, none when empty."),
+		lite:                 flag.Bool("ice-lite", false, "Run ICE-lite, for an answer side with a public IP."),
+	}
+}
+
+// Apply overrides the [ice] table of cfg with the flags given on the command line
+func (f *ICEFlags) Apply(cfg *Config) {
+	Override(cfg, &cfg.ICE.PortMin, "ice-port-min", *f.portMin)
+	Override(cfg, &cfg.ICE.PortMax, "ice-port-max", *f.portMax)
+	overrideList(&cfg.ICE.NAT1To1IPs, "ice-nat-1to1-ips", *f.nat1To1IPs)
+	Override(cfg, &cfg.ICE.NAT1To1CandidateType, "ice-nat-1to1-candidate-type", *f.nat1To1CandidateType)
+	overrideList(&cfg.ICE.NetworkTypes, "ice-network-types", *f.networkTypes)
+	overrideList(&cfg.ICE.Interfaces, "ice-interfaces", *f.interfaces)
+	overrideList(&cfg.ICE.ExcludeInterfaces, "ice-exclude-interfaces", *f.excludeInterfaces)
+	Override(cfg, &cfg.ICE.MDNS, "ice-mdns", *f.mdns)
+	Override(cfg, &cfg.ICE.DisconnectedTimeout, "ice-disconnected-timeout", *f.disconnectedTimeout)
+	Override(cfg, &cfg.ICE.FailedTimeout, "ice-failed-timeout", *f.failedTimeout)
+	Override(cfg, &cfg.ICE.KeepaliveInterval, "ice-keepalive-interval", *f.keepaliveInterval)
+	Override(cfg, &cfg.ICE.TCPListenAddress, "ice-tcp-listen-address", *f.tcpListenAddress)
+	Override(cfg, &cfg.ICE.Lite, "ice-lite", *f.lite)
+}
+
+// overrideList is Override for comma separated lists, flags of lists have no default
+func overrideList(field *[]string, name string, value string) {
+	if flagGiven(name) {
+		*field = splitList(value)
+	}
+}
+
+// splitList splits comma separated values, an empty string into none
+func splitList(s string) []string {
+	values := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func parseDuration(key, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("ice: %s: %w", key, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/ice/v2"
+	"github.com/pion/stun"
+	"github.com/pion/webrtc/v3"
+)
+
+// iceTCPTimeout bounds how long the ICE-TCP check may take to connect
+const iceTCPTimeout = 15 * time.Second
+
+// freeAddress is a free TCP port on all interfaces, candidates advertise the
+// interface addresses rather than loopback
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	if err := listener.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return address
+}
+
+// iceCredentials are the ice-ufrag and ice-pwd of an SDP
+func iceCredentials(t *testing.T, sdp string) (ufrag, pwd string) {
+	t.Helper()
+
+	for _, line := range strings.Split(sdp, "\r\n") {
+		switch {
+		case strings.HasPrefix(line, "a=ice-ufrag:") && ufrag == "":
+			ufrag = strings.TrimPrefix(line, "a=ice-ufrag:")
+		case strings.HasPrefix(line, "a=ice-pwd:") && pwd == "":
+			pwd = strings.TrimPrefix(line, "a=ice-pwd:")
+		}
+	}
+	if ufrag == "" || pwd == "" {
+		t.Fatal("SDP without ice-ufrag or ice-pwd")
+	}
+	return ufrag, pwd
+}
+
+// passiveTCPCandidate is the passive ICE-TCP candidate of an SDP
+func passiveTCPCandidate(t *testing.T, sdp string) ice.Candidate {
+	t.Helper()
+
+	for _, line := range strings.Split(sdp, "\r\n") {
+		if !strings.HasPrefix(line, "a=candidate:") {
+			continue
+		}
+		c, err := ice.UnmarshalCandidate(strings.TrimPrefix(line, "a="))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.NetworkType().IsTCP() && c.TCPType() == ice.TCPTypePassive {
+			return c
+		}
+	}
+	t.Fatalf("no passive TCP candidate in\n%s", sdp)
+	return nil
+}
+
+// writeFrame writes a STUN message with the RFC 4571 length prefix of ICE-TCP
+func writeFrame(conn net.Conn, m *stun.Message) error {
+	frame := make([]byte, 2+len(m.Raw))
+	binary.BigEndian.PutUint16(frame, uint16(len(m.Raw)))
+	copy(frame[2:], m.Raw)
+	_, err := conn.Write(frame)
+	return err
+}
+
+// readFrame reads one RFC 4571 frame
+func readFrame(conn net.Conn) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	frame := make([]byte, binary.BigEndian.Uint16(header))
+	_, err := io.ReadFull(conn, frame)
+	return frame, err
+}
+
+// TestICETCPOnly connects an answer side that gathers nothing but a passive
+// ICE-TCP candidate on tcp_listen_address. Pion never dials ICE-TCP, so the
+// active side is a minimal controlling agent that runs the STUN checks itself.
+func TestICETCPOnly(t *testing.T) {
+	offerer, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = offerer.Close() }()
+	if _, err := offerer.CreateDataChannel("data", nil); err != nil {
+		t.Fatal(err)
+	}
+	offer, err := offerer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offerUfrag, offerPwd := iceCredentials(t, offer.SDP)
+
+	cfg := Default()
+	cfg.ICE.NetworkTypes = []string{"tcp4"}
+	cfg.ICE.TCPListenAddress = freeAddress(t)
+	api, err := cfg.API()
+	if err != nil {
+		t.Fatal(err)
+	}
+	answerer, err := api.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = answerer.Close() }()
+
+	connected := make(chan struct{})
+	answerer.OnICEConnectionStateChange(func(s webrtc.ICEConnectionState) {
+		if s == webrtc.ICEConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	if err := answerer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := answerer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gathered := webrtc.GatheringCompletePromise(answerer)
+	if err := answerer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-gathered:
+	case <-time.After(iceTCPTimeout):
+		t.Fatal("timed out gathering candidates")
+	}
+	sdp := answerer.LocalDescription().SDP
+	for _, line := range strings.Split(sdp, "\r\n") {
+		if strings.HasPrefix(line, "a=candidate:") && !strings.Contains(line, " tcp ") {
+			t.Fatalf("candidate %q with tcp4 only", line)
+		}
+	}
+	answerUfrag, answerPwd := iceCredentials(t, sdp)
+	candidate := passiveTCPCandidate(t, sdp)
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(candidate.Address(), strconv.Itoa(candidate.Port())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// nominate checks the pair as controlling agent, the first request also
+	// tells the listener which PeerConnection the connection belongs to
+	nominate := func() error {
+		m, err := stun.Build(stun.BindingRequest, stun.TransactionID,
+			stun.NewUsername(answerUfrag+":"+offerUfrag),
+			ice.AttrControlling(1),
+			ice.UseCandidate(),
+			ice.PriorityAttr(candidate.Priority()),
+			stun.NewShortTermIntegrity(answerPwd),
+			stun.Fingerprint,
+		)
+		if err != nil {
+			return err
+		}
+		return writeFrame(conn, m)
+	}
+	if err := nominate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Answer the checks of the answer side, a pair is only nominated once
+	// both directions succeeded, so nominate again after each answer
+	go func() {
+		for {
+			frame, err := readFrame(conn)
+			if err != nil {
+				return
+			}
+			m := &stun.Message{Raw: frame}
+			if !stun.IsMessage(frame) || m.Decode() != nil || m.Type != stun.BindingRequest {
+				continue
+			}
+			addr := conn.LocalAddr().(*net.TCPAddr)
+			response, err := stun.Build(m, stun.BindingSuccess,
+				&stun.XORMappedAddress{IP: addr.IP, Port: addr.Port},
+				stun.NewShortTermIntegrity(offerPwd),
+				stun.Fingerprint,
+			)
+			if err != nil || writeFrame(conn, response) != nil || nominate() != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-connected:
+	case <-time.After(iceTCPTimeout):
+		t.Fatal("timed out waiting for ICE over TCP to connect")
+	}
+	pair, err := answerer.SCTP().Transport().ICETransport().GetSelectedCandidatePair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair == nil || pair.Local.Protocol != webrtc.ICEProtocolTCP {
+		t.Fatalf("selected pair %v, want a TCP pair", pair)
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"flag"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func TestAPISharesTCPListener(t *testing.T) {
+	// A free port, the listener of API is the only one on it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	if err := listener.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Default()
+	cfg.ICE.TCPListenAddress = address
+	for i := 0; i < 3; i++ {
+		if _, err := cfg.API(); err != nil {
+			t.Fatalf("API %d: %v", i, err)
+		}
+	}
+}
+
+func TestAPIGathersTCPByDefault(t *testing.T) {
+	cfg := Default()
+	cfg.ICE.TCPListenAddress = freeAddress(t)
+
+	tcp, udp := 0, 0
+	for _, c := range gatherHostCandidates(t, cfg) {
+		if c.Protocol == webrtc.ICEProtocolTCP {
+			tcp++
+		} else {
+			udp++
+		}
+	}
+	if tcp == 0 || udp == 0 {
+		t.Fatalf("got %d TCP and %d UDP candidates without network_types, want both", tcp, udp)
+	}
+}
+
+func TestSettingEngineRejects(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"port range", "[ice]\nport_min = 0\nport_max = 70000\n", "port range"},
+		{"inverted port range", "[ice]\nport_min = 50100\nport_max = 50000\n", "port range"},
+		{"nat candidate type", "[ice]\nnat_1to1_ips = [\"203.0.113.7\"]\nnat_1to1_candidate_type = \"relay\"\n", "nat_1to1_candidate_type"},
+		{"network type", "[ice]\nnetwork_types = [\"sctp\"]\n", "network_types"},
+		{"mdns", "[ice]\nmdns = \"loud\"\n", "mdns"},
+		{"timeout", "[ice]\nfailed_timeout = \"soon\"\n", "failed_timeout"},
+		{"lite with srflx", "[ice]\nlite = true\nnat_1to1_ips = [\"203.0.113.7\"]\nnat_1to1_candidate_type = \"srflx\"\n", "lite"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadTOML(t, test.content)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got %v, want an error with %q", err, test.want)
+			}
+		})
+	}
+}
+
+// gatherHostCandidates gathers the candidates of a PeerConnection of cfg
+func gatherHostCandidates(t *testing.T, cfg Config) []webrtc.ICECandidate {
+	t.Helper()
+
+	api, err := cfg.API()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := api.NewPeerConnection(cfg.WebRTCConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = pc.Close()
+	})
+	if _, err := pc.CreateDataChannel("data", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates := []webrtc.ICECandidate{}
+	gathered := make(chan struct{})
+	pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+		if c == nil {
+			close(gathered)
+			return
+		}
+		candidates = append(candidates, *c)
+	})
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-gathered:
+	case <-time.After(10 * time.Second):
+		t.Fatal("gathering did not complete")
+	}
+	return candidates
+}
+
+func TestSettingEngineGathering(t *testing.T) {
+	cfg, err := loadTOML(t, "[ice]\nport_min = 50000\nport_max = 50100\nnetwork_types = [\"udp4\"]\nnat_1to1_ips = [\"203.0.113.7\"]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	candidates := gatherHostCandidates(t, cfg)
+	if len(candidates) == 0 {
+		t.Fatal("no candidates gathered")
+	}
+	for _, c := range candidates {
+		if c.Protocol != webrtc.ICEProtocolUDP || c.Typ != webrtc.ICECandidateTypeHost {
+			t.Errorf("candidate %s, want udp host candidates only", c)
+		}
+		if c.Address != "203.0.113.7" {
+			t.Errorf("candidate %s, want the NAT 1:1 IP", c)
+		}
+		if c.Port < 50000 || c.Port > 50100 {
+			t.Errorf("candidate %s, want a port in 50000-50100", c)
+		}
+	}
+
+	// No interface is left to gather on
+	cfg.ICE.ExcludeInterfaces = []string{}
+	cfg.ICE.Interfaces = []string{"no-such-interface"}
+	if candidates := gatherHostCandidates(t, cfg); len(candidates) != 0 {
+		t.Fatalf("got %d candidates on a missing interface, want none", len(candidates))
+	}
+}
+
+func TestICEFlagsApply(t *testing.T) {
+	commandLine := flag.CommandLine
+	t.Cleanup(func() { flag.CommandLine = commandLine })
+	flag.CommandLine = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+
+	iceFlags := NewICEFlags()
+	if err := flag.CommandLine.Parse([]string{"--ice-port-min", "40000", "--ice-network-types", "udp4, tcp4", "--ice-lite"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadTOML(t, "[ice]\nport_min = 50000\nport_max = 50100\nmdns = \"disabled\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	iceFlags.Apply(&cfg)
+
+	want := ICEConfig{PortMin: 40000, PortMax: 50100, NetworkTypes: []string{"udp4", "tcp4"}, MDNS: "disabled", Lite: true}
+	if !reflect.DeepEqual(cfg.ICE, want) {
+		t.Fatalf("got %+v, want %+v", cfg.ICE, want)
+	}
+}
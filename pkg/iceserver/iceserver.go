@@ -0,0 +1,57 @@
+// Package iceserver hosts the ICE servers a demo can run in process,
+// so peers connect without servers on the internet
+package iceserver
+
+import (
+	"errors"
+	"log"
+
+	"webrtc-demo/pkg/config"
+
+	"github.com/pion/turn/v2"
+)
+
+// Servers are the STUN and TURN servers a process hosts for its peers
+type Servers struct {
+	stun *STUNServer
+	turn *turn.Server
+}
+
+// Start hosts the servers of the [stun] and [turn] tables of cfg, none when
+// neither is set. Close the Servers when the process is done.
+func Start(cfg config.Config) (*Servers, error) {
+	s := &Servers{}
+
+	if cfg.STUN.Enabled() {
+		stunServer, err := StartSTUN(cfg.STUN.ListenAddress)
+		if err != nil {
+			return nil, err
+		}
+		s.stun = stunServer
+		log.Printf("STUN server listening on %s\n", stunServer.Addr())
+	}
+
+	if cfg.TURN.Enabled() {
+		turnServer, err := StartTURN(cfg.TURN)
+		if err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		s.turn = turnServer
+		log.Printf("TURN server listening on %s\n", cfg.TURN.ListenAddress)
+	}
+
+	return s, nil
+}
+
+// Close stops every server
+func (s *Servers) Close() error {
+	var errs []error
+	if s.stun != nil {
+		errs = append(errs, s.stun.Close())
+	}
+	if s.turn != nil {
+		errs = append(errs, s.turn.Close())
+	}
+	return errors.Join(errs...)
+}
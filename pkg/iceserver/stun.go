@@ -0,0 +1,90 @@
+package iceserver
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/pion/stun"
+)
+
+// stunBufferSize fits any STUN message sent over UDP
+const stunBufferSize = 1500
+
+// STUNServer answers STUN Binding requests over UDP with the address they came
+// from, all ICE agents need to find their server reflexive candidates
+type STUNServer struct {
+	conn      net.PacketConn
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// StartSTUN serves STUN on the UDP address addr, e.g. ":3478"
+func StartSTUN(addr string) (*STUNServer, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("stun: %w", err)
+	}
+
+	s := &STUNServer{conn: conn, done: make(chan struct{})}
+	go s.serve()
+	return s, nil
+}
+
+// Addr is the address the server listens on
+func (s *STUNServer) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// Close stops the server
+func (s *STUNServer) Close() error {
+	err := error(nil)
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.conn.Close()
+	})
+	return err
+}
+
+func (s *STUNServer) serve() {
+	buf := make([]byte, stunBufferSize)
+	for {
+		n, from, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				log.Println("stun server stopped:", err)
+			}
+			return
+		}
+
+		// Anything but a Binding request is dropped, as RFC 5389 asks of servers
+		if !stun.IsMessage(buf[:n]) {
+			continue
+		}
+		req := &stun.Message{Raw: append([]byte{}, buf[:n]...)}
+		if err := req.Decode(); err != nil || req.Type != stun.BindingRequest {
+			continue
+		}
+		udpAddr, ok := from.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+
+		res, err := stun.Build(
+			stun.NewTransactionIDSetter(req.TransactionID),
+			stun.BindingSuccess,
+			&stun.XORMappedAddress{IP: udpAddr.IP, Port: udpAddr.Port},
+			stun.Fingerprint,
+		)
+		if err != nil {
+			log.Println("cannot build stun response:", err)
+			continue
+		}
+		if _, err := s.conn.WriteTo(res.Raw, from); err != nil {
+			log.Println("cannot write stun response:", err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package iceserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"webrtc-demo/pkg/config"
+
+	"github.com/pion/stun"
+	"github.com/pion/webrtc/v3"
+)
+
+func TestSTUNServerBinding(t *testing.T) {
+	server, err := StartSTUN("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+
+	conn, err := net.Dial("udp", server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	// Anything but a Binding request goes unanswered, the server keeps serving
+	if _, err := conn.Write([]byte("not stun")); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := stun.NewClient(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped := stun.XORMappedAddress{}
+	var doErr error
+	if err := client.Do(stun.MustBuild(stun.TransactionID, stun.BindingRequest), func(e stun.Event) {
+		if doErr = e.Error; doErr == nil {
+			doErr = mapped.GetFrom(e.Message)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if doErr != nil {
+		t.Fatal(doErr)
+	}
+
+	local := conn.LocalAddr().(*net.UDPAddr)
+	if !mapped.IP.Equal(local.IP) || mapped.Port != local.Port {
+		t.Fatalf("mapped address %s, want %s", mapped, local)
+	}
+}
+
+// gatherCandidateTypes gathers the candidates of a PeerConnection of cfg and returns their types
+func gatherCandidateTypes(t *testing.T, cfg config.Config) map[webrtc.ICECandidateType]bool {
+	t.Helper()
+
+	api, err := cfg.API()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := api.NewPeerConnection(cfg.WebRTCConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = pc.Close()
+	})
+	if _, err := pc.CreateDataChannel("data", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	types := map[webrtc.ICECandidateType]bool{}
+	gathered := make(chan struct{})
+	pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+		if c == nil {
+			close(gathered)
+			return
+		}
+		types[c.Typ] = true
+	})
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-gathered:
+	case <-time.After(connectTimeout):
+		t.Fatal("gathering did not complete")
+	}
+	return types
+}
+
+func TestSTUNServerReflexiveCandidates(t *testing.T) {
+	// Without ICE servers only host candidates are gathered
+	cfg := config.Default()
+	cfg.ICE.NetworkTypes = []string{"udp4"}
+	if types := gatherCandidateTypes(t, cfg); types[webrtc.ICECandidateTypeSrflx] || !types[webrtc.ICECandidateTypeHost] {
+		t.Fatalf("got candidate types %v without ICE servers, want host only", types)
+	}
+
+	cfg.STUN.ListenAddress = freeAddress(t)
+	servers, err := Start(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = servers.Close()
+	})
+
+	cfg.ICEServers = []config.ICEServer{{URLs: []string{"stun:" + cfg.STUN.ListenAddress}}}
+	if types := gatherCandidateTypes(t, cfg); !types[webrtc.ICECandidateTypeSrflx] {
+		t.Fatalf("got candidate types %v, want a server reflexive one", types)
+	}
+}
@@ -0,0 +1,73 @@
+package iceserver
+
+import (
+	"fmt"
+	"net"
+
+	"webrtc-demo/pkg/config"
+
+	"github.com/pion/turn/v2"
+)
+
+// relayBindAddress is where relayed sockets are bound, cfg.PublicIP is what peers are told
+const relayBindAddress = "0.0.0.0"
+
+// StartTURN serves TURN over UDP and TCP on cfg.ListenAddress for the single
+// user of cfg, relaying from cfg.PublicIP. The user needs a username and a
+// password. Close the server to stop it.
+func StartTURN(cfg config.TURNConfig) (*turn.Server, error) {
+	if cfg.Username == "" || cfg.Password == "" {
+		return nil, fmt.Errorf("turn: username and password are required")
+	}
+	publicIP := net.ParseIP(cfg.PublicIP)
+	if publicIP == nil {
+		return nil, fmt.Errorf("turn: public_ip %q is not an IP address", cfg.PublicIP)
+	}
+
+	udpListener, err := net.ListenPacket("udp4", cfg.ListenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("turn: %w", err)
+	}
+	tcpListener, err := net.Listen("tcp4", cfg.ListenAddress)
+	if err != nil {
+		_ = udpListener.Close()
+		return nil, fmt.Errorf("turn: %w", err)
+	}
+
+	// Long-term credentials, the key is all the server needs to check a request
+	authKey := turn.GenerateAuthKey(cfg.Username, cfg.Realm, cfg.Password)
+
+	server, err := turn.NewServer(turn.ServerConfig{
+		Realm: cfg.Realm,
+		AuthHandler: func(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+			if username != cfg.Username || realm != cfg.Realm {
+				return nil, false
+			}
+			return authKey, true
+		},
+		PacketConnConfigs: []turn.PacketConnConfig{
+			{
+				PacketConn: udpListener,
+				RelayAddressGenerator: &turn.RelayAddressGeneratorStatic{
+					RelayAddress: publicIP,
+					Address:      relayBindAddress,
+				},
+			},
+		},
+		ListenerConfigs: []turn.ListenerConfig{
+			{
+				Listener: tcpListener,
+				RelayAddressGenerator: &turn.RelayAddressGeneratorStatic{
+					RelayAddress: publicIP,
+					Address:      relayBindAddress,
+				},
+			},
+		},
+	})
+	if err != nil {
+		_ = udpListener.Close()
+		_ = tcpListener.Close()
+		return nil, fmt.Errorf("turn: %w", err)
+	}
+	return server, nil
+}
@@ -0,0 +1,147 @@
+package iceserver
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"webrtc-demo/pkg/config"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// connectTimeout bounds how long the relayed pair may take to connect
+const connectTimeout = 15 * time.Second
+
+// freeAddress is a loopback port that is free for TCP, StartTURN takes UDP on it as well
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	if err := listener.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return address
+}
+
+// newRelayPeerConnection reaches other peers through the TURN server of cfg only
+func newRelayPeerConnection(t *testing.T, cfg config.Config) (*webrtc.PeerConnection, <-chan struct{}) {
+	t.Helper()
+
+	api, err := cfg.API()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := api.NewPeerConnection(cfg.WebRTCConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = pc.Close()
+	})
+
+	connected := make(chan struct{})
+	pc.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		if s == webrtc.PeerConnectionStateConnected {
+			close(connected)
+		}
+	})
+	return pc, connected
+}
+
+// setLocalDescription applies desc and waits until every candidate is in it
+func setLocalDescription(t *testing.T, pc *webrtc.PeerConnection, desc webrtc.SessionDescription) webrtc.SessionDescription {
+	t.Helper()
+
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(desc); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-gathered:
+	case <-time.After(connectTimeout):
+		t.Fatal("timed out gathering candidates")
+	}
+	return *pc.LocalDescription()
+}
+
+func TestTURNRelayOnly(t *testing.T) {
+	cfg := config.Default()
+	cfg.TURN = config.TURNConfig{
+		ListenAddress: freeAddress(t),
+		PublicIP:      "127.0.0.1",
+		Realm:         "webrtc-demo",
+		Username:      "demo",
+		Password:      "secret",
+	}
+	server, err := StartTURN(cfg.TURN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }()
+
+	cfg.ICETransportPolicy = "relay"
+	cfg.ICEServers = []config.ICEServer{{
+		URLs:       []string{"turn:" + cfg.TURN.ListenAddress},
+		Username:   cfg.TURN.Username,
+		Credential: cfg.TURN.Password,
+	}}
+	offerer, offererConnected := newRelayPeerConnection(t, cfg)
+	answerer, answererConnected := newRelayPeerConnection(t, cfg)
+
+	if _, err := offerer.CreateDataChannel("data", nil); err != nil {
+		t.Fatal(err)
+	}
+	offer, err := offerer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offer = setLocalDescription(t, offerer, offer)
+	if err := answerer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := answerer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer = setLocalDescription(t, answerer, answer)
+	if err := offerer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Relay only: every candidate of both sides is a relayed one
+	for _, desc := range []webrtc.SessionDescription{offer, answer} {
+		for _, line := range strings.Split(desc.SDP, "\r\n") {
+			if strings.HasPrefix(line, "a=candidate:") && !strings.Contains(line, "typ relay") {
+				t.Fatalf("candidate %q with ice_transport_policy relay", line)
+			}
+		}
+	}
+
+	for _, connected := range []<-chan struct{}{offererConnected, answererConnected} {
+		select {
+		case <-connected:
+		case <-time.After(connectTimeout):
+			t.Fatal("timed out connecting through TURN")
+		}
+	}
+	pair, err := offerer.SCTP().Transport().ICETransport().GetSelectedCandidatePair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair == nil || pair.Local.Typ != webrtc.ICECandidateTypeRelay {
+		t.Fatalf("selected pair %v, want a relayed local candidate", pair)
+	}
+}
+
+func TestStartTURNRequiresCredentials(t *testing.T) {
+	_, err := StartTURN(config.TURNConfig{ListenAddress: freeAddress(t), PublicIP: "127.0.0.1", Username: "demo"})
+	if err == nil {
+		t.Fatal("StartTURN accepted a user without password")
+	}
+}
@@ -0,0 +1,179 @@
+package peer
+
+import (
+	"sync"
+
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Option configures a Peer, see New
+type Option func(*options)
+
+type dataChannel struct {
+	label  string
+	handle func(*webrtc.DataChannel)
+}
+
+type options struct {
+	tracks       []webrtc.TrackLocal
+	dataChannels []dataChannel
+
+	onTrack                 func(*webrtc.TrackRemote, *webrtc.RTPReceiver)
+	onDataChannel           func(*webrtc.DataChannel)
+	onConnectionStateChange func(webrtc.PeerConnectionState)
+	onGiveUp                func()
+	onSessionDescription    func(webrtc.SessionDescription)
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithTrack sends track to the remote peer. Its RTCP is read and dropped, so
+// the interceptors of the sender keep working.
+func WithTrack(track webrtc.TrackLocal) Option {
+	return func(o *options) {
+		o.tracks = append(o.tracks, track)
+	}
+}
+
+// WithDataChannel creates a data channel with label and hands it to handle
+// right away, register OnOpen and OnMessage there
+func WithDataChannel(label string, handle func(*webrtc.DataChannel)) Option {
+	return func(o *options) {
+		o.dataChannels = append(o.dataChannels, dataChannel{label: label, handle: handle})
+	}
+}
+
+// OnTrack sets the handler for tracks of the remote peer
+func OnTrack(f func(*webrtc.TrackRemote, *webrtc.RTPReceiver)) Option {
+	return func(o *options) {
+		o.onTrack = f
+	}
+}
+
+// OnDataChannel sets the handler for data channels the remote peer created
+func OnDataChannel(f func(*webrtc.DataChannel)) Option {
+	return func(o *options) {
+		o.onDataChannel = f
+	}
+}
+
+// OnConnectionStateChange sets the handler for the state of the PeerConnection,
+// it is called during ICE restarts too
+func OnConnectionStateChange(f func(webrtc.PeerConnectionState)) Option {
+	return func(o *options) {
+		o.onConnectionStateChange = f
+	}
+}
+
+// OnGiveUp sets the handler for a connection that did not recover, the Peer
+// is closed right after it and Run returns ErrNotRecovered
+func OnGiveUp(f func()) Option {
+	return func(o *options) {
+		o.onGiveUp = f
+	}
+}
+
+// OnSessionDescription sets the handler for every offer and answer, local and
+// remote, e.g. to print them with sdputil.Describe
+func OnSessionDescription(f func(webrtc.SessionDescription)) Option {
+	return func(o *options) {
+		o.onSessionDescription = f
+	}
+}
+
+// apply adds the tracks and data channels of o to pc and registers its handlers
+func (o *options) apply(pc *webrtc.PeerConnection) error {
+	for _, track := range o.tracks {
+		rtpSender, err := pc.AddTrack(track)
+		if err != nil {
+			return err
+		}
+		go func() {
+			buf := make([]byte, 1500)
+			for {
+				if _, _, err := rtpSender.Read(buf); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	for _, d := range o.dataChannels {
+		dataChannel, err := pc.CreateDataChannel(d.label, nil)
+		if err != nil {
+			return err
+		}
+		if d.handle != nil {
+			d.handle(dataChannel)
+		}
+	}
+
+	if o.onTrack != nil {
+		pc.OnTrack(o.onTrack)
+	}
+	if o.onDataChannel != nil {
+		pc.OnDataChannel(o.onDataChannel)
+	}
+	return nil
+}
+
+// observedSignaler hands every offer and answer, sent or received, to onDescription
+type observedSignaler struct {
+	signaling.Signaler
+	onDescription func(webrtc.SessionDescription)
+	recv          chan signaling.Message
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+func newObservedSignaler(signaler signaling.Signaler, onDescription func(webrtc.SessionDescription)) *observedSignaler {
+	s := &observedSignaler{
+		Signaler:      signaler,
+		onDescription: onDescription,
+		recv:          make(chan signaling.Message),
+		done:          make(chan struct{}),
+	}
+	go func() {
+		defer close(s.recv)
+		for msg := range signaler.Recv() {
+			if msg.SDP != nil {
+				onDescription(*msg.SDP)
+			}
+			select {
+			case s.recv <- msg:
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *observedSignaler) SendOffer(offer webrtc.SessionDescription) error {
+	s.onDescription(offer)
+	return s.Signaler.SendOffer(offer)
+}
+
+func (s *observedSignaler) SendAnswer(answer webrtc.SessionDescription) error {
+	s.onDescription(answer)
+	return s.Signaler.SendAnswer(answer)
+}
+
+func (s *observedSignaler) Recv() <-chan signaling.Message {
+	return s.recv
+}
+
+func (s *observedSignaler) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	return s.Signaler.Close()
+}
@@ -0,0 +1,180 @@
+// Package peer is a PeerConnection together with its signaling, candidate
+// trickling and ICE restarts, so a program only says what it sends and what it
+// does with what it receives.
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// ErrNotRecovered is returned by Run when the connection was lost and ICE restarts did not bring it back
+var ErrNotRecovered = errors.New("peer connection did not recover")
+
+// Role is the side a Peer plays in the negotiation
+type Role string
+
+const (
+	// Offerer sends the first offer and restarts ICE when the connection is lost,
+	// it is the impolite peer of perfect negotiation
+	Offerer Role = "offerer"
+	// Answerer answers and waits for the ICE restarts of the Offerer
+	Answerer Role = "answerer"
+)
+
+// Peer is one PeerConnection negotiated over a Signaler.
+//
+// Tracks and data channels of the options are added right away, the Offerer
+// then sends its offer. Either side may renegotiate later, e.g. after AddTrack
+// on PeerConnection. Candidates are trickled, held back until the remote
+// description is set. A connection that stays disconnected or fails is
+// recovered with ICE restarts, see signaling.ICERestarter.
+type Peer struct {
+	role       Role
+	pc         *webrtc.PeerConnection
+	signaler   signaling.Signaler
+	negotiator *signaling.Negotiator
+
+	mu        sync.Mutex
+	gaveUp    bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Open opens the signaling transport of cfg.Signaling for role, HTTP when none
+// is set, and negotiates a Peer over it. Over WebSocket and gRPC the Answerer
+// blocks until the Offerer connected.
+func Open(ctx context.Context, role Role, cfg config.Config, opts ...Option) (*Peer, error) {
+	transport := cfg.Signaling.Transport
+	if transport == "" {
+		transport = signaling.TransportHTTP
+	}
+
+	signalingRole := signaling.RoleOffer
+	if role == Answerer {
+		signalingRole = signaling.RoleAnswer
+	}
+
+	signaler, err := signaling.OpenSignaler(ctx, signalingRole, signaling.SignalerOptions{
+		Transport:  transport,
+		ListenAddr: cfg.Signaling.ListenAddress,
+		RemoteAddr: cfg.Signaling.RemoteAddress,
+		RedisURL:   cfg.Signaling.RedisURL,
+		SessionID:  cfg.Signaling.Session,
+		Dir:        cfg.Signaling.Dir,
+		Config:     cfg,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := New(role, cfg, signaler, opts...)
+	if err != nil {
+		_ = signaler.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// New creates the PeerConnection of cfg, see config.Config.API, and negotiates
+// it over signaler. The Peer owns signaler from now on, Close closes it.
+func New(role Role, cfg config.Config, signaler signaling.Signaler, opts ...Option) (*Peer, error) {
+	o := newOptions(opts)
+	if o.onSessionDescription != nil {
+		signaler = newObservedSignaler(signaler, o.onSessionDescription)
+	}
+
+	api, err := cfg.API()
+	if err != nil {
+		return nil, err
+	}
+	pc, err := api.NewPeerConnection(cfg.WebRTCConfiguration())
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Peer{
+		role:     role,
+		pc:       pc,
+		signaler: signaler,
+	}
+	p.negotiator = signaling.NewNegotiator(pc, signaler, role == Answerer)
+
+	// The Offerer restarts ICE, the Answerer waits for its restarts
+	restart := p.negotiator.RestartICE
+	if role == Answerer {
+		restart = nil
+	}
+	restarter := signaling.NewICERestarter(pc, restart, signaling.ICERestartOptions{})
+	if o.onConnectionStateChange != nil {
+		restarter.OnConnectionStateChange(o.onConnectionStateChange)
+	}
+	restarter.OnGiveUp(func() {
+		p.mu.Lock()
+		p.gaveUp = true
+		p.mu.Unlock()
+
+		if o.onGiveUp != nil {
+			o.onGiveUp()
+		}
+		if err := p.Close(); err != nil {
+			log.Println("cannot close peer:", err)
+		}
+	})
+
+	if err := o.apply(pc); err != nil {
+		_ = pc.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Role is the side the Peer plays
+func (p *Peer) Role() Role {
+	return p.role
+}
+
+// PeerConnection is the PeerConnection of the Peer. Its OnConnectionStateChange,
+// OnICECandidate and OnNegotiationNeeded belong to the Peer, use the options.
+func (p *Peer) PeerConnection() *webrtc.PeerConnection {
+	return p.pc
+}
+
+// Run negotiates with the remote peer until it says bye or the Peer is closed.
+// It returns ErrNotRecovered when the connection was lost for good, or the
+// first error of the negotiation or the signaling transport.
+func (p *Peer) Run() error {
+	err := p.negotiator.Run()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.gaveUp {
+		return ErrNotRecovered
+	}
+	return err
+}
+
+// Close says bye to the remote peer, closes the signaling transport and the
+// PeerConnection with its tracks and data channels
+func (p *Peer) Close() error {
+	p.closeOnce.Do(func() {
+		var errs []error
+		if err := p.signaler.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot close signaler: %w", err))
+		}
+		if err := p.pc.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot close peerConnection: %w", err))
+		}
+		p.closeErr = errors.Join(errs...)
+	})
+	return p.closeErr
+}
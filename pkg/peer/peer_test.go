@@ -0,0 +1,124 @@
+package peer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// testTimeout bounds how long a loopback pair may take to exchange a message
+const testTimeout = 15 * time.Second
+
+// run runs p until the test ends and reports its error
+func run(t *testing.T, p *Peer) <-chan error {
+	t.Helper()
+
+	errs := make(chan error, 1)
+	go func() { errs <- p.Run() }()
+	t.Cleanup(func() {
+		_ = p.Close()
+	})
+	return errs
+}
+
+// ping sends "ping" on a data channel as soon as it opens and hands the reply to replies
+func ping(replies chan<- string) func(*webrtc.DataChannel) {
+	return func(d *webrtc.DataChannel) {
+		d.OnOpen(func() {
+			_ = d.SendText("ping")
+		})
+		d.OnMessage(func(msg webrtc.DataChannelMessage) {
+			replies <- string(msg.Data)
+		})
+	}
+}
+
+// echo replies to every message of a data channel the remote peer opened
+func echo(d *webrtc.DataChannel) {
+	d.OnMessage(func(msg webrtc.DataChannelMessage) {
+		_ = d.SendText("echo " + string(msg.Data))
+	})
+}
+
+func waitReply(t *testing.T, replies <-chan string) {
+	t.Helper()
+
+	select {
+	case reply := <-replies:
+		if reply != "echo ping" {
+			t.Fatalf("got %q, want %q", reply, "echo ping")
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the reply")
+	}
+}
+
+func TestOffererAnswerer(t *testing.T) {
+	cfg := config.Default()
+	a, b := signaling.NewMemorySignalers()
+
+	replies := make(chan string, 1)
+	offerer, err := New(Offerer, cfg, a, WithDataChannel("data", ping(replies)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	answerer, err := New(Answerer, cfg, b, OnDataChannel(echo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offererErrs := run(t, offerer)
+	answererErrs := run(t, answerer)
+
+	waitReply(t, replies)
+
+	// Close says bye, so both sides stop without an error
+	if err := offerer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, errs := range []<-chan error{offererErrs, answererErrs} {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("timed out waiting for Run to return")
+		}
+	}
+}
+
+func TestSessionServer(t *testing.T) {
+	cfg := config.Default()
+
+	sessions, err := NewSessionServer(cfg, func(id string) []Option {
+		return []Option{OnDataChannel(echo)}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(sessions)
+	defer server.Close()
+
+	// Two offerers at once, each in its own session
+	for _, id := range []string{"first", "second"} {
+		replies := make(chan string, 1)
+		session := signaling.DialSession(http.DefaultClient, server.URL, id)
+		offerer, err := New(Offerer, cfg, signaling.NewConnSignaler(session), WithDataChannel("data", ping(replies)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		run(t, offerer)
+
+		waitReply(t, replies)
+	}
+	if n := sessions.Len(); n != 2 {
+		t.Fatalf("%d sessions, want 2", n)
+	}
+}
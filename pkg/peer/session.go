@@ -0,0 +1,32 @@
+package peer
+
+import (
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// NewSessionServer answers many Offerers at once, see signaling.SessionServer.
+// Every session gets a PeerConnection of cfg with the options of sessionOptions.
+// The server owns the connection state and the candidates of each session, so
+// only the tracks, data channels, OnTrack and OnDataChannel options apply.
+func NewSessionServer(cfg config.Config, sessionOptions func(id string) []Option) (*signaling.SessionServer, error) {
+	api, err := cfg.API()
+	if err != nil {
+		return nil, err
+	}
+	configuration := cfg.WebRTCConfiguration()
+
+	return signaling.NewSessionServer(func(id string) (*webrtc.PeerConnection, error) {
+		pc, err := api.NewPeerConnection(configuration)
+		if err != nil {
+			return nil, err
+		}
+		if err := newOptions(sessionOptions(id)).apply(pc); err != nil {
+			_ = pc.Close()
+			return nil, err
+		}
+		return pc, nil
+	}), nil
+}
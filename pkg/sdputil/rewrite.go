@@ -0,0 +1,186 @@
+package sdputil
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+)
+
+var errNoCodecs = errors.New("no codec left in media section")
+
+// Rewrite changes a parsed session description in place
+type Rewrite func(*sdp.SessionDescription) error
+
+// Apply runs rewrites on desc in order and returns the rewritten description
+func Apply(desc webrtc.SessionDescription, rewrites ...Rewrite) (webrtc.SessionDescription, error) {
+	parsed, err := desc.Unmarshal()
+	if err != nil {
+		return desc, err
+	}
+
+	for _, rewrite := range rewrites {
+		if err := rewrite(parsed); err != nil {
+			return desc, err
+		}
+	}
+
+	raw, err := parsed.Marshal()
+	if err != nil {
+		return desc, err
+	}
+	return webrtc.SessionDescription{Type: desc.Type, SDP: string(raw)}, nil
+}
+
+// KeepCodecs restricts audio and video sections to the codecs named, e.g. "H264" or "opus".
+// Names are case insensitive, retransmission (rtx) formats are kept for kept codecs,
+// and so are attributes of every format, e.g. a=rtcp-fb:* nack.
+// A section left without a codec is an error.
+func KeepCodecs(names ...string) Rewrite {
+	return func(s *sdp.SessionDescription) error {
+		for _, m := range s.MediaDescriptions {
+			if m.MediaName.Media != "audio" && m.MediaName.Media != "video" {
+				continue
+			}
+
+			kept := keptPayloadTypes(m, names)
+			if len(kept) == 0 {
+				return fmt.Errorf("%w: m=%s", errNoCodecs, m.MediaName.Media)
+			}
+
+			formats := m.MediaName.Formats[:0]
+			for _, format := range m.MediaName.Formats {
+				if kept[format] {
+					formats = append(formats, format)
+				}
+			}
+			m.MediaName.Formats = formats
+
+			attributes := m.Attributes[:0]
+			for _, a := range m.Attributes {
+				if a.Key == "rtpmap" || a.Key == "fmtp" || a.Key == "rtcp-fb" {
+					format, _, _ := strings.Cut(a.Value, " ")
+					if format != "*" && !kept[format] {
+						continue
+					}
+				}
+				attributes = append(attributes, a)
+			}
+			m.Attributes = attributes
+		}
+		return nil
+	}
+}
+
+// keptPayloadTypes returns the formats of m whose codec is in names, plus their rtx
+func keptPayloadTypes(m *sdp.MediaDescription, names []string) map[string]bool {
+	kept := map[string]bool{}
+	rtx := map[string]string{}
+
+	for _, a := range m.Attributes {
+		format, value, _ := strings.Cut(a.Value, " ")
+		switch a.Key {
+		case "rtpmap":
+			name, _, _ := strings.Cut(value, "/")
+			for _, wanted := range names {
+				if strings.EqualFold(name, wanted) {
+					kept[format] = true
+				}
+			}
+		case "fmtp":
+			if apt, ok := fmtpParameter(value, "apt"); ok {
+				rtx[format] = apt
+			}
+		}
+	}
+
+	for format, apt := range rtx {
+		if kept[apt] {
+			kept[format] = true
+		}
+	}
+	return kept
+}
+
+func fmtpParameter(fmtp, key string) (string, bool) {
+	for _, param := range strings.Split(fmtp, ";") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(param), "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// SetBandwidth sets b=AS (kbps) and b=TIAS (bps) of the sections of kind,
+// e.g. "video", to bitrate in bits per second. An empty kind sets every section.
+func SetBandwidth(kind string, bitrate uint64) Rewrite {
+	return func(s *sdp.SessionDescription) error {
+		for _, m := range s.MediaDescriptions {
+			if kind != "" && m.MediaName.Media != kind {
+				continue
+			}
+
+			bandwidth := []sdp.Bandwidth{
+				{Type: "AS", Bandwidth: bitrate / 1000},
+				{Type: "TIAS", Bandwidth: bitrate},
+			}
+			for _, b := range m.Bandwidth {
+				if b.Type != "AS" && b.Type != "TIAS" {
+					bandwidth = append(bandwidth, b)
+				}
+			}
+			m.Bandwidth = bandwidth
+		}
+		return nil
+	}
+}
+
+// StripCandidates removes candidates of the given types, e.g. "host" to hide local addresses
+func StripCandidates(types ...string) Rewrite {
+	return func(s *sdp.SessionDescription) error {
+		for _, m := range s.MediaDescriptions {
+			attributes := m.Attributes[:0]
+			for _, a := range m.Attributes {
+				if a.IsICECandidate() && hasCandidateType(a.Value, types) {
+					continue
+				}
+				attributes = append(attributes, a)
+			}
+			m.Attributes = attributes
+		}
+		return nil
+	}
+}
+
+func hasCandidateType(value string, types []string) bool {
+	c, err := ParseCandidate(value)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		if c.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseBitrate parses a bitrate such as 2500000, 2500k or 2.5M into bits per second
+func ParseBitrate(value string) (uint64, error) {
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(value, "k"), strings.HasSuffix(value, "K"):
+		multiplier, value = 1e3, value[:len(value)-1]
+	case strings.HasSuffix(value, "m"), strings.HasSuffix(value, "M"):
+		multiplier, value = 1e6, value[:len(value)-1]
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("bad bitrate %q", value)
+	}
+	return uint64(f * multiplier), nil
+}
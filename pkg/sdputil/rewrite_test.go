@@ -0,0 +1,73 @@
+package sdputil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const testOffer = "v=0\r\n" +
+	"o=- 1 2 IN IP4 127.0.0.1\r\n" +
+	"s=-\r\n" +
+	"t=0 0\r\n" +
+	"m=video 9 UDP/TLS/RTP/SAVPF 96 97 98 99\r\n" +
+	"c=IN IP4 0.0.0.0\r\n" +
+	"a=mid:0\r\n" +
+	"a=rtpmap:96 H264/90000\r\n" +
+	"a=fmtp:96 packetization-mode=1\r\n" +
+	"a=rtcp-fb:96 nack\r\n" +
+	"a=rtpmap:97 rtx/90000\r\n" +
+	"a=fmtp:97 apt=96\r\n" +
+	"a=rtpmap:98 VP8/90000\r\n" +
+	"a=rtcp-fb:98 nack\r\n" +
+	"a=rtpmap:99 rtx/90000\r\n" +
+	"a=fmtp:99 apt=98\r\n" +
+	"a=rtcp-fb:* transport-cc\r\n" +
+	"a=candidate:1 1 udp 2130706431 192.168.1.2 5000 typ host\r\n" +
+	"a=candidate:2 1 udp 1694498815 203.0.113.7 5000 typ srflx raddr 192.168.1.2 rport 5000\r\n"
+
+func TestKeepCodecs(t *testing.T) {
+	desc, err := Apply(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: testOffer}, KeepCodecs("h264"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"m=video 9 UDP/TLS/RTP/SAVPF 96 97\r\n", "a=rtcp-fb:96 nack", "a=fmtp:97 apt=96", "a=rtcp-fb:* transport-cc"} {
+		if !strings.Contains(desc.SDP, want) {
+			t.Errorf("rewritten SDP lacks %q:\n%s", want, desc.SDP)
+		}
+	}
+	for _, dropped := range []string{"VP8", "a=rtcp-fb:98", "apt=98"} {
+		if strings.Contains(desc.SDP, dropped) {
+			t.Errorf("rewritten SDP still has %q:\n%s", dropped, desc.SDP)
+		}
+	}
+
+	if _, err := Apply(desc, KeepCodecs("opus")); !errors.Is(err, errNoCodecs) {
+		t.Fatalf("KeepCodecs(opus) = %v, want %v", err, errNoCodecs)
+	}
+}
+
+func TestStripCandidates(t *testing.T) {
+	desc, err := Apply(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: testOffer}, StripCandidates("host"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(desc.SDP, "typ host") || !strings.Contains(desc.SDP, "typ srflx") {
+		t.Fatalf("got\n%s\nwant the srflx candidate only", desc.SDP)
+	}
+}
+
+func TestParseBitrate(t *testing.T) {
+	tests := map[string]uint64{"2500000": 2500000, "2500k": 2500000, "2.5M": 2500000, "64K": 64000}
+	for in, want := range tests {
+		if got, err := ParseBitrate(in); err != nil || got != want {
+			t.Errorf("ParseBitrate(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseBitrate("-1k"); err == nil {
+		t.Error("ParseBitrate(-1k) took a negative bitrate")
+	}
+}
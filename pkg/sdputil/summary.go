@@ -0,0 +1,248 @@
+// Package sdputil inspects and rewrites the SDP of webrtc.SessionDescriptions.
+//
+// Inspect turns an offer or answer into a Summary of what is negotiated,
+// Apply runs Rewrites such as KeepCodecs, SetBandwidth or StripCandidates on it.
+package sdputil
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+)
+
+var errBadCandidate = errors.New("malformed candidate")
+
+// Summary is what a session description negotiates
+type Summary struct {
+	Type         string   `json:"type"`
+	Fingerprints []string `json:"fingerprints,omitempty"`
+	ICEUfrag     string   `json:"iceUfrag,omitempty"`
+	Media        []Media  `json:"media"`
+}
+
+// Media is a single m= section
+type Media struct {
+	Kind         string      `json:"kind"`
+	Mid          string      `json:"mid,omitempty"`
+	Port         int         `json:"port"`
+	Protocol     string      `json:"protocol"`
+	Direction    string      `json:"direction"`
+	Bandwidth    []string    `json:"bandwidth,omitempty"`
+	Codecs       []Codec     `json:"codecs,omitempty"`
+	Candidates   []Candidate `json:"candidates,omitempty"`
+	Fingerprints []string    `json:"fingerprints,omitempty"`
+	ICEUfrag     string      `json:"iceUfrag,omitempty"`
+}
+
+// Codec is a payload type of a media section with its rtpmap, fmtp and rtcp-fb lines
+type Codec struct {
+	PayloadType  uint8    `json:"payloadType"`
+	Name         string   `json:"name"`
+	ClockRate    uint32   `json:"clockRate,omitempty"`
+	Channels     string   `json:"channels,omitempty"`
+	Fmtp         string   `json:"fmtp,omitempty"`
+	RTCPFeedback []string `json:"rtcpFeedback,omitempty"`
+}
+
+// Candidate is an a=candidate line of a media section
+type Candidate struct {
+	Foundation string `json:"foundation"`
+	Component  string `json:"component"`
+	Protocol   string `json:"protocol"`
+	Priority   uint32 `json:"priority"`
+	Address    string `json:"address"`
+	Port       int    `json:"port"`
+	Type       string `json:"type"`
+}
+
+// Inspect parses desc into a Summary
+func Inspect(desc webrtc.SessionDescription) (*Summary, error) {
+	parsed, err := desc.Unmarshal()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{Type: desc.Type.String()}
+	for _, a := range parsed.Attributes {
+		switch a.Key {
+		case "fingerprint":
+			summary.Fingerprints = append(summary.Fingerprints, a.Value)
+		case "ice-ufrag":
+			summary.ICEUfrag = a.Value
+		}
+	}
+
+	for _, m := range parsed.MediaDescriptions {
+		summary.Media = append(summary.Media, inspectMedia(m))
+	}
+
+	return summary, nil
+}
+
+func inspectMedia(m *sdp.MediaDescription) Media {
+	media := Media{
+		Kind:      m.MediaName.Media,
+		Port:      m.MediaName.Port.Value,
+		Protocol:  strings.Join(m.MediaName.Protos, "/"),
+		Direction: "sendrecv",
+	}
+	for _, b := range m.Bandwidth {
+		media.Bandwidth = append(media.Bandwidth, b.String())
+	}
+
+	codecs := map[uint8]*Codec{}
+	for _, format := range m.MediaName.Formats {
+		pt, err := strconv.ParseUint(format, 10, 8)
+		if err != nil {
+			// e.g. webrtc-datachannel, it has no payload types
+			continue
+		}
+		media.Codecs = append(media.Codecs, Codec{PayloadType: uint8(pt)})
+	}
+	for i := range media.Codecs {
+		codecs[media.Codecs[i].PayloadType] = &media.Codecs[i]
+	}
+
+	for _, a := range m.Attributes {
+		switch a.Key {
+		case "mid":
+			media.Mid = a.Value
+		case "sendrecv", "sendonly", "recvonly", "inactive":
+			media.Direction = a.Key
+		case "fingerprint":
+			media.Fingerprints = append(media.Fingerprints, a.Value)
+		case "ice-ufrag":
+			media.ICEUfrag = a.Value
+		case "candidate":
+			if c, err := ParseCandidate(a.Value); err == nil {
+				media.Candidates = append(media.Candidates, c)
+			}
+		case "rtpmap", "fmtp", "rtcp-fb":
+			pt, value, ok := splitPayloadType(a.Value)
+			if codec := codecs[pt]; ok && codec != nil {
+				addCodecAttribute(codec, a.Key, value)
+			}
+		}
+	}
+
+	return media
+}
+
+// addCodecAttribute fills codec from the value of a rtpmap, fmtp or rtcp-fb line
+func addCodecAttribute(codec *Codec, key, value string) {
+	switch key {
+	case "rtpmap":
+		// <encoding name>/<clock rate>[/<encoding parameters>]
+		parts := strings.Split(value, "/")
+		codec.Name = parts[0]
+		if len(parts) > 1 {
+			if rate, err := strconv.ParseUint(parts[1], 10, 32); err == nil {
+				codec.ClockRate = uint32(rate)
+			}
+		}
+		if len(parts) > 2 {
+			codec.Channels = parts[2]
+		}
+	case "fmtp":
+		codec.Fmtp = value
+	case "rtcp-fb":
+		codec.RTCPFeedback = append(codec.RTCPFeedback, value)
+	}
+}
+
+// splitPayloadType splits "<payload type> <value>" of a rtpmap, fmtp or rtcp-fb line
+func splitPayloadType(value string) (uint8, string, bool) {
+	format, rest, _ := strings.Cut(value, " ")
+	pt, err := strconv.ParseUint(format, 10, 8)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint8(pt), rest, true
+}
+
+// ParseCandidate parses the value of an a=candidate line, with or without the "candidate:" prefix
+func ParseCandidate(value string) (Candidate, error) {
+	fields := strings.Fields(strings.TrimPrefix(value, "candidate:"))
+	if len(fields) < 8 || fields[6] != "typ" {
+		return Candidate{}, fmt.Errorf("%w: %q", errBadCandidate, value)
+	}
+
+	priority, err := strconv.ParseUint(fields[3], 10, 32)
+	if err != nil {
+		return Candidate{}, fmt.Errorf("%w: %q", errBadCandidate, value)
+	}
+	port, err := strconv.Atoi(fields[5])
+	if err != nil {
+		return Candidate{}, fmt.Errorf("%w: %q", errBadCandidate, value)
+	}
+
+	return Candidate{
+		Foundation: fields[0],
+		Component:  fields[1],
+		Protocol:   strings.ToLower(fields[2]),
+		Priority:   uint32(priority),
+		Address:    fields[4],
+		Port:       port,
+		Type:       fields[7],
+	}, nil
+}
+
+// Describe returns the Summary of desc as text, or why desc cannot be parsed
+func Describe(desc webrtc.SessionDescription) string {
+	summary, err := Inspect(desc)
+	if err != nil {
+		return fmt.Sprintf("%s: cannot inspect sdp: %v\n", desc.Type, err)
+	}
+	return summary.String()
+}
+
+// String prints the summary one media section per block, e.g. for logging what was negotiated
+func (s *Summary) String() string {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "%s", s.Type)
+	if s.ICEUfrag != "" {
+		fmt.Fprintf(b, " ice-ufrag=%s", s.ICEUfrag)
+	}
+	b.WriteString("\n")
+	for _, f := range s.Fingerprints {
+		fmt.Fprintf(b, "  fingerprint %s\n", f)
+	}
+
+	for _, m := range s.Media {
+		fmt.Fprintf(b, "  m=%s mid=%s %s %s port=%d", m.Kind, m.Mid, m.Direction, m.Protocol, m.Port)
+		if m.ICEUfrag != "" && m.ICEUfrag != s.ICEUfrag {
+			fmt.Fprintf(b, " ice-ufrag=%s", m.ICEUfrag)
+		}
+		if len(m.Bandwidth) > 0 {
+			fmt.Fprintf(b, " b=%s", strings.Join(m.Bandwidth, ","))
+		}
+		b.WriteString("\n")
+
+		for _, f := range m.Fingerprints {
+			fmt.Fprintf(b, "    fingerprint %s\n", f)
+		}
+		for _, c := range m.Codecs {
+			fmt.Fprintf(b, "    %d %s/%d", c.PayloadType, c.Name, c.ClockRate)
+			if c.Channels != "" {
+				fmt.Fprintf(b, "/%s", c.Channels)
+			}
+			if c.Fmtp != "" {
+				fmt.Fprintf(b, " fmtp:%s", c.Fmtp)
+			}
+			if len(c.RTCPFeedback) > 0 {
+				fmt.Fprintf(b, " rtcp-fb:%s", strings.Join(c.RTCPFeedback, ","))
+			}
+			b.WriteString("\n")
+		}
+		for _, c := range m.Candidates {
+			fmt.Fprintf(b, "    candidate %s %s %s:%d priority=%d\n", c.Type, c.Protocol, c.Address, c.Port, c.Priority)
+		}
+	}
+
+	return b.String()
+}
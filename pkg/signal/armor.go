@@ -0,0 +1,187 @@
+package signal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultChunkSize keeps every armored chunk small enough for a readable terminal QR code
+const DefaultChunkSize = 800
+
+// armorLineLength is the width of the body lines of an armored chunk
+const armorLineLength = 64
+
+var (
+	armorBegin = regexp.MustCompile(`^-----BEGIN WEBRTC SIGNAL (\d+)/(\d+) ([0-9a-f]{8})-----$`)
+	armorEnd   = regexp.MustCompile(`^-----END WEBRTC SIGNAL (\d+)/(\d+)-----$`)
+)
+
+// Armor splits a blob of Encode into numbered chunks of at most chunkSize characters:
+//
+//	-----BEGIN WEBRTC SIGNAL 1/3 1a2b3c4d-----
+//	<blob, 64 characters per line>
+//	-----END WEBRTC SIGNAL 1/3-----
+//
+// The hex tag is a checksum of the whole blob, so chunks of different blobs are
+// never mixed up when they are pasted back. chunkSize <= 0 uses DefaultChunkSize.
+func Armor(blob string, chunkSize int) []string {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	total := (len(blob) + chunkSize - 1) / chunkSize
+	if total == 0 {
+		total = 1
+	}
+	tag := armorTag(blob)
+
+	chunks := make([]string, 0, total)
+	for i := 0; i < total; i++ {
+		part := blob[i*chunkSize : min(len(blob), (i+1)*chunkSize)]
+
+		b := &strings.Builder{}
+		fmt.Fprintf(b, "-----BEGIN WEBRTC SIGNAL %d/%d %s-----\n", i+1, total, tag)
+		for len(part) > armorLineLength {
+			b.WriteString(part[:armorLineLength] + "\n")
+			part = part[armorLineLength:]
+		}
+		if part != "" {
+			b.WriteString(part + "\n")
+		}
+		fmt.Fprintf(b, "-----END WEBRTC SIGNAL %d/%d-----\n", i+1, total)
+
+		chunks = append(chunks, b.String())
+	}
+
+	return chunks
+}
+
+func armorTag(blob string) string {
+	sum := sha256.Sum256([]byte(blob))
+	return hex.EncodeToString(sum[:4])
+}
+
+// Reassembler puts a blob back together from armored chunks pasted line by line.
+// Chunks may come in any order, lines outside of chunks are ignored, e.g. the
+// captions and QR codes of WriteManual.
+type Reassembler struct {
+	tag    string
+	total  int
+	chunks map[int]string
+
+	current int
+	body    strings.Builder
+	blob    string
+}
+
+// NewReassembler creates an empty Reassembler
+func NewReassembler() *Reassembler {
+	return &Reassembler{chunks: map[int]string{}}
+}
+
+// Feed consumes a single line and reports whether the blob is complete.
+// A non-armored line before any chunk is taken as a whole blob if it decodes,
+// like a blob pasted in one line.
+func (r *Reassembler) Feed(line string) (bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || r.blob != "" {
+		return r.blob != "", nil
+	}
+
+	if m := armorBegin.FindStringSubmatch(line); m != nil {
+		index, _ := strconv.Atoi(m[1])
+		total, _ := strconv.Atoi(m[2])
+		if index < 1 || index > total {
+			return false, &Error{Kind: ErrBadArmor, Err: fmt.Errorf("chunk %d of %d", index, total)}
+		}
+		if r.tag != "" && (m[3] != r.tag || total != r.total) {
+			return false, &Error{Kind: ErrBadArmor, Err: fmt.Errorf("chunk %d/%d %s belongs to another blob than %s", index, total, m[3], r.tag)}
+		}
+
+		r.tag, r.total, r.current = m[3], total, index
+		r.body.Reset()
+		return false, nil
+	}
+
+	if m := armorEnd.FindStringSubmatch(line); m != nil {
+		if index, _ := strconv.Atoi(m[1]); index != r.current {
+			return false, &Error{Kind: ErrBadArmor, Err: fmt.Errorf("END of chunk %s without its BEGIN", m[1])}
+		}
+		r.chunks[r.current] = r.body.String()
+		r.current = 0
+
+		return r.complete()
+	}
+
+	switch {
+	case r.current > 0:
+		r.body.WriteString(line)
+	case r.tag == "" && Unmarshal(line, &json.RawMessage{}) == nil:
+		r.blob = line
+	}
+	return r.blob != "", nil
+}
+
+func (r *Reassembler) complete() (bool, error) {
+	if len(r.chunks) < r.total {
+		return false, nil
+	}
+
+	b := &strings.Builder{}
+	for i := 1; i <= r.total; i++ {
+		b.WriteString(r.chunks[i])
+	}
+	if tag := armorTag(b.String()); tag != r.tag {
+		return false, &Error{Kind: ErrBadArmor, Err: fmt.Errorf("checksum %s does not match %s", tag, r.tag)}
+	}
+
+	r.blob = b.String()
+	return true, nil
+}
+
+// Missing returns the numbers of the chunks that were not pasted yet
+func (r *Reassembler) Missing() []int {
+	missing := []int{}
+	for i := 1; i <= r.total; i++ {
+		if _, ok := r.chunks[i]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// Blob returns the reassembled blob, it is empty until Feed reported completion
+func (r *Reassembler) Blob() string {
+	return r.blob
+}
+
+// ReadArmored blocks until a whole blob is read or ctx is done.
+// The blob may be pasted as armored chunks of Armor or as a single line.
+//
+// When ctx is done first the chunks read so far are dropped and the line being
+// read is kept for the next call.
+func (lr *LineReader) ReadArmored(ctx context.Context) (string, error) {
+	reassembler := NewReassembler()
+	for {
+		in, err := lr.next(ctx)
+		complete, feedErr := reassembler.Feed(in)
+		switch {
+		case feedErr != nil:
+			return "", feedErr
+		case complete:
+			return reassembler.Blob(), nil
+		case err != nil:
+			if err == io.EOF && reassembler.total > 0 {
+				err = &Error{Kind: ErrBadArmor, Err: fmt.Errorf("missing chunks %v: %w", reassembler.Missing(), err)}
+			}
+			return "", err
+		}
+	}
+}
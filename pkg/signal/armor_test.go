@@ -0,0 +1,113 @@
+package signal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testBlob is a blob of Encode long enough for a few chunks
+func testBlob(t *testing.T) string {
+	t.Helper()
+
+	blob, err := Marshal(map[string]string{"type": "offer", "sdp": strings.Repeat("a=candidate:1 1 udp 2130706431 127.0.0.1 5000 typ host\r\n", 40)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob
+}
+
+func TestArmorReassemble(t *testing.T) {
+	blob := testBlob(t)
+	chunks := Armor(blob, 0)
+	if len(chunks) < 3 {
+		t.Fatalf("got %d chunks, want a few", len(chunks))
+	}
+	for _, chunk := range chunks {
+		for _, line := range strings.Split(strings.TrimSpace(chunk), "\n") {
+			if len(line) > armorLineLength && !strings.HasPrefix(line, "-----") {
+				t.Fatalf("body line of %d characters", len(line))
+			}
+		}
+	}
+
+	// Pasted in reverse order, with chatter between the chunks
+	pasted := &strings.Builder{}
+	for i := len(chunks) - 1; i >= 0; i-- {
+		pasted.WriteString(chunks[i] + "here is the next one\n\n")
+	}
+	got, err := NewLineReader(strings.NewReader(pasted.String())).ReadArmored(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != blob {
+		t.Fatalf("got %q, want %q", got, blob)
+	}
+
+	// A blob pasted in one line needs no armor
+	if got, err := NewLineReader(strings.NewReader(blob + "\n")).ReadArmored(context.Background()); err != nil || got != blob {
+		t.Fatalf("single line: got %q, %v", got, err)
+	}
+}
+
+func TestArmorErrors(t *testing.T) {
+	chunks := Armor(testBlob(t), 0)
+	other := Armor(strings.Repeat("b", len(testBlob(t))), 0)
+
+	tests := []struct {
+		name, pasted string
+		want         error
+	}{
+		{"missing chunk", chunks[0] + chunks[2], io.EOF},
+		{"chunk of another blob", chunks[0] + other[1], ErrBadArmor},
+		{"end without begin", "-----END WEBRTC SIGNAL 1/3-----\n", ErrBadArmor},
+		{"chunk out of range", strings.Replace(chunks[0], "SIGNAL 1/", "SIGNAL 9/", 1), ErrBadArmor},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewLineReader(strings.NewReader(test.pasted)).ReadArmored(context.Background())
+			if !errors.Is(err, test.want) {
+				t.Fatalf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	// The missing chunks are named
+	r := NewReassembler()
+	for _, line := range strings.Split(chunks[1], "\n") {
+		if _, err := r.Feed(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if missing := r.Missing(); len(missing) != len(chunks)-1 || missing[0] != 1 {
+		t.Fatalf("missing %v, want every chunk but 2", missing)
+	}
+}
+
+func TestWriteManual(t *testing.T) {
+	blob := testBlob(t)
+	b := &bytes.Buffer{}
+	if err := WriteManual(b, blob, 0, true); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := Armor(blob, 0)
+	out := b.String()
+	for i, chunk := range chunks {
+		code, err := QRCode(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, code+chunk) {
+			t.Fatalf("chunk %d does not follow its QR code", i+1)
+		}
+	}
+
+	// The output pasted back as printed, captions and QR codes included
+	if got, err := NewLineReader(strings.NewReader(out)).ReadArmored(context.Background()); err != nil || got != blob {
+		t.Fatalf("got %q, %v, want the blob", got, err)
+	}
+}
@@ -0,0 +1,147 @@
+package signal
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects how Marshal shrinks the JSON before base64 encoding it.
+//
+// Every compressed blob starts with a short format prefix, e.g. "gz:", so
+// Unmarshal detects the format by itself and both peers don't have to agree
+// beforehand. Blobs without a prefix are plain base64 JSON.
+type Compression string
+
+const (
+	CompressionNone    Compression = "none"
+	CompressionGzip    Compression = "gzip"
+	CompressionDeflate Compression = "deflate"
+	CompressionZstd    Compression = "zstd"
+)
+
+// DefaultCompression is used by Encode and Marshal, set it from a flag with ParseCompression.
+var DefaultCompression = CompressionNone
+
+// MaxDecompressedBytes caps the JSON Unmarshal inflates from a compressed blob,
+// so a small blob cannot expand into gigabytes
+const MaxDecompressedBytes = 1 << 20
+
+var errTooLarge = fmt.Errorf("decompresses to more than %d bytes", MaxDecompressedBytes)
+
+// prefixes of the compressed formats, ':' is not in the base64 alphabet
+var compressionPrefixes = map[Compression]string{
+	CompressionGzip:    "gz:",
+	CompressionDeflate: "df:",
+	CompressionZstd:    "zs:",
+}
+
+// ParseCompression returns the Compression named s
+func ParseCompression(s string) (Compression, error) {
+	switch c := Compression(strings.ToLower(s)); c {
+	case CompressionNone, CompressionGzip, CompressionDeflate, CompressionZstd:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown compression %q, use none, gzip, deflate or zstd", s)
+}
+
+// splitCompression detects the format of a blob and strips its prefix
+func splitCompression(in string) (Compression, string) {
+	for c, prefix := range compressionPrefixes {
+		if strings.HasPrefix(in, prefix) {
+			return c, strings.TrimPrefix(in, prefix)
+		}
+	}
+	return CompressionNone, in
+}
+
+func compressBytes(c Compression, in []byte) ([]byte, error) {
+	switch c {
+	case CompressionNone:
+		return in, nil
+	case CompressionGzip:
+		return zip(in)
+	case CompressionDeflate:
+		var b bytes.Buffer
+		w, err := flate.NewWriter(&b, flate.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(in); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	case CompressionZstd:
+		w, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		if err != nil {
+			return nil, err
+		}
+		defer w.Close()
+		return w.EncodeAll(in, nil), nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", c)
+}
+
+func decompressBytes(c Compression, in []byte) ([]byte, error) {
+	switch c {
+	case CompressionNone:
+		return in, nil
+	case CompressionGzip:
+		return unzip(in)
+	case CompressionDeflate:
+		r := flate.NewReader(bytes.NewReader(in))
+		defer r.Close()
+		return readLimited(r)
+	case CompressionZstd:
+		r, err := zstd.NewReader(bytes.NewReader(in))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readLimited(r)
+	}
+	return nil, fmt.Errorf("unknown compression %q", c)
+}
+
+func zip(in []byte) ([]byte, error) {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	if _, err := gz.Write(in); err != nil {
+		return nil, err
+	}
+	if err := gz.Flush(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func unzip(in []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(in))
+	if err != nil {
+		return nil, err
+	}
+	return readLimited(r)
+}
+
+// readLimited reads r to the end, or fails once it exceeds MaxDecompressedBytes
+func readLimited(r io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, MaxDecompressedBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > MaxDecompressedBytes {
+		return nil, errTooLarge
+	}
+	return b, nil
+}
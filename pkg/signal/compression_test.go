@@ -0,0 +1,49 @@
+package signal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var compressions = []Compression{CompressionNone, CompressionGzip, CompressionDeflate, CompressionZstd}
+
+func TestCompressionRoundTrip(t *testing.T) {
+	in := map[string]string{"type": "offer", "sdp": strings.Repeat("a=candidate:1 1 udp 2130706431 127.0.0.1 5000 typ host\r\n", 20)}
+	for _, c := range compressions {
+		t.Run(string(c), func(t *testing.T) {
+			blob, err := MarshalWith(in, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c != CompressionNone && !strings.HasPrefix(blob, compressionPrefixes[c]) {
+				t.Fatalf("blob %.8q... lacks the prefix %q", blob, compressionPrefixes[c])
+			}
+
+			out := map[string]string{}
+			if err := Unmarshal(blob, &out); err != nil {
+				t.Fatal(err)
+			}
+			if out["sdp"] != in["sdp"] {
+				t.Fatalf("got %q, want %q", out["sdp"], in["sdp"])
+			}
+		})
+	}
+}
+
+func TestDecompressionLimit(t *testing.T) {
+	bomb := strings.Repeat("0", MaxDecompressedBytes)
+	for _, c := range compressions[1:] {
+		t.Run(string(c), func(t *testing.T) {
+			blob, err := MarshalWith(bomb, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out := ""
+			if err := Unmarshal(blob, &out); !errors.Is(err, ErrBadCompression) || !errors.Is(err, errTooLarge) {
+				t.Fatalf("Unmarshal() of %d compressed bytes = %v, want %v", len(blob), err, errTooLarge)
+			}
+		})
+	}
+}
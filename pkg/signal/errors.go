@@ -0,0 +1,38 @@
+package signal
+
+import "errors"
+
+var (
+	// ErrBadBase64 is returned when a pasted blob is not valid base64
+	ErrBadBase64 = errors.New("signal: bad base64")
+	// ErrBadCompression is returned when a blob cannot be compressed or decompressed
+	ErrBadCompression = errors.New("signal: bad compression")
+	// ErrBadJSON is returned when a value cannot be marshaled to or unmarshaled from JSON
+	ErrBadJSON = errors.New("signal: bad json")
+	// ErrBadArmor is returned when pasted chunks cannot be reassembled into a blob
+	ErrBadArmor = errors.New("signal: bad armor")
+	// ErrRandom is returned when no random data can be read
+	ErrRandom = errors.New("signal: no randomness")
+)
+
+// Error wraps the cause of a failed Marshal, Unmarshal or RandString call.
+// Use errors.Is with ErrBadBase64, ErrBadCompression, ErrBadJSON or ErrRandom
+// to tell what went wrong, errors.As still reaches the underlying error.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the Kind of e
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
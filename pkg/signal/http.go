@@ -1,24 +1,27 @@
 package signal
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"strconv"
 )
 
+// MaxSDPBytes caps the body of a request to HTTPSDPServer, an SDP is a few KiB.
+// It is the MaxBodyBytes of signaling.DefaultLimits as well.
+const MaxSDPBytes = 64 << 10
+
 // HTTPSDPServer starts a HTTP Server that consumes SDPs
+//
+// All SDPs share one channel, signaling.SessionServer scopes them by session ID.
 func HTTPSDPServer() chan string {
 	port := flag.Int("port", 8080, "http server port")
 	flag.Parse()
 
 	sdpChan := make(chan string)
-	http.HandleFunc("/sdp", func(w http.ResponseWriter, r *http.Request) {
-		body, _ := ioutil.ReadAll(r.Body)
-		fmt.Fprintf(w, "done")
-		sdpChan <- string(body)
-	})
+	http.HandleFunc("/sdp", sdpHandler(sdpChan))
 
 	go func() {
 		err := http.ListenAndServe(":"+strconv.Itoa(*port), nil)
@@ -29,3 +32,21 @@ func HTTPSDPServer() chan string {
 
 	return sdpChan
 }
+
+// sdpHandler pushes the body of every request into sdpChan
+func sdpHandler(sdpChan chan<- string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxSDPBytes))
+		if err != nil {
+			// Like signaling.BodyErrorStatus, signaling imports this package
+			status := http.StatusBadRequest
+			if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		fmt.Fprintf(w, "done")
+		sdpChan <- string(body)
+	}
+}
@@ -0,0 +1,43 @@
+package signal
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// failingReader fails every read, like a client that went away mid body
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestSDPHandlerStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *http.Request
+		want int
+	}{
+		{"too large", httptest.NewRequest(http.MethodPost, "/sdp", strings.NewReader(strings.Repeat("a", MaxSDPBytes+1))), http.StatusRequestEntityTooLarge},
+		{"read error", httptest.NewRequest(http.MethodPost, "/sdp", failingReader{}), http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			sdpHandler(make(chan string))(w, test.r)
+			if w.Code != test.want {
+				t.Fatalf("status %d, want %d", w.Code, test.want)
+			}
+		})
+	}
+
+	sdpChan := make(chan string, 1)
+	w := httptest.NewRecorder()
+	sdpHandler(sdpChan)(w, httptest.NewRequest(http.MethodPost, "/sdp", strings.NewReader("v=0")))
+	if w.Code != http.StatusOK || <-sdpChan != "v=0" {
+		t.Fatalf("status %d, want the SDP to go through", w.Code)
+	}
+}
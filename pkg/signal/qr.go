@@ -0,0 +1,38 @@
+package signal
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// QRCode renders s as a QR code for the terminal, two modules per text row
+func QRCode(s string) (string, error) {
+	code, err := qrcode.New(s, qrcode.Low)
+	if err != nil {
+		return "", err
+	}
+	return code.ToSmallString(false), nil
+}
+
+// WriteManual writes a blob of Encode for copying by hand: every armored chunk,
+// preceded by its QR code when withQR is set. LineReader.ReadArmored reads it back.
+func WriteManual(w io.Writer, blob string, chunkSize int, withQR bool) error {
+	chunks := Armor(blob, chunkSize)
+	for i, chunk := range chunks {
+		if withQR {
+			code, err := QRCode(chunk)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "Chunk %d of %d, scan it or copy the text below\n%s", i+1, len(chunks), code); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
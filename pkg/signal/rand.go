@@ -2,15 +2,27 @@ package signal
 
 import "github.com/pion/randutil"
 
+const runesAlpha = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
 // RandSeq generates a random string to serve as dummy data
 //
 // It returns a deterministic sequence of values each time a program is run.
 // Use rand.Seed() function in your real applications.
 func RandSeq(n int) string {
-	val, err := randutil.GenerateCryptoRandomString(n, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	val, err := RandString(n)
 	if err != nil {
 		panic(err)
 	}
 
 	return val
 }
+
+// RandString is RandSeq that returns an *Error instead of panicking
+func RandString(n int) (string, error) {
+	val, err := randutil.GenerateCryptoRandomString(n, runesAlpha)
+	if err != nil {
+		return "", &Error{Kind: ErrRandom, Err: err}
+	}
+
+	return val, nil
+}
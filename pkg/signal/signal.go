@@ -4,108 +4,165 @@ package signal
 
 import (
 	"bufio"
-	"bytes"
-	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"strings"
+	"sync"
 )
 
-// Allows compressing offer/answer to bypass terminal input limits.
-const compress = false
+// stdin is the LineReader of MustReadStdin
+var stdin = NewLineReader(os.Stdin)
 
 // MustReadStdin blocks until input is received from stdin
 func MustReadStdin() string {
-	r := bufio.NewReader(os.Stdin)
+	in, err := stdin.ReadLine(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("")
+
+	return in
+}
 
-	var in string
+// LineReader reads the lines of a stream for ReadLine and ReadArmored, keep
+// one per stream: it buffers what it read ahead.
+//
+// The stream is only read while a call waits for a line. When ctx is done
+// first the line being read is kept for the next call, so the caller should
+// close the stream if it can block forever. Concurrent calls take turns.
+type LineReader struct {
+	br    *bufio.Reader
+	lines chan lineResult
+	// turn is held by the call waiting for a line
+	turn chan struct{}
+
+	mu      sync.Mutex
+	reading bool
+}
+
+type lineResult struct {
+	line string
+	err  error
+}
+
+// NewLineReader creates a LineReader of r
+func NewLineReader(r io.Reader) *LineReader {
+	return &LineReader{
+		br:    bufio.NewReader(r),
+		lines: make(chan lineResult, 1),
+		turn:  make(chan struct{}, 1),
+	}
+}
+
+// ReadLine blocks until a non-empty line is read or ctx is done.
+// The line is returned without surrounding whitespace.
+func (lr *LineReader) ReadLine(ctx context.Context) (string, error) {
 	for {
-		var err error
-		in, err = r.ReadString('\n')
-		if err != io.EOF {
-			if err != nil {
-				panic(err)
-			}
-		}
+		in, err := lr.next(ctx)
 		in = strings.TrimSpace(in)
 		if len(in) > 0 {
-			break
+			return in, nil
+		}
+		if err != nil {
+			return "", err
 		}
 	}
+}
 
-	fmt.Println("")
+// next returns the next line of the stream, including its newline.
+// A line is either being read or waits in lines, never both.
+func (lr *LineReader) next(ctx context.Context) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case lr.turn <- struct{}{}:
+	}
+	defer func() { <-lr.turn }()
 
-	return in
+	lr.mu.Lock()
+	if !lr.reading && len(lr.lines) == 0 {
+		lr.reading = true
+		go lr.read()
+	}
+	lr.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case res := <-lr.lines:
+		return res.line, res.err
+	}
+}
+
+func (lr *LineReader) read() {
+	in, err := lr.br.ReadString('\n')
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	lr.lines <- lineResult{line: in, err: err}
+	lr.reading = false
 }
 
 // Encode encodes the input in base64
-// It can optionally zip the input before encoding
+// It compresses the input with DefaultCompression before encoding
 func Encode(obj interface{}) string {
-	b, err := json.Marshal(obj)
+	out, err := Marshal(obj)
 	if err != nil {
 		panic(err)
 	}
 
-	if compress {
-		b = zip(b)
-	}
-
-	return base64.StdEncoding.EncodeToString(b)
+	return out
 }
 
 // Decode decodes the input from base64
-// It can optionally unzip the input after decoding
+// It detects the compression of the input by its prefix and decompresses it after decoding
 func Decode(in string, obj interface{}) {
-	b, err := base64.StdEncoding.DecodeString(in)
-	if err != nil {
+	if err := Unmarshal(in, obj); err != nil {
 		panic(err)
 	}
+}
 
-	if compress {
-		b = unzip(b)
-	}
-
-	err = json.Unmarshal(b, obj)
-	if err != nil {
-		panic(err)
-	}
+// Marshal is Encode that returns an *Error instead of panicking
+func Marshal(obj interface{}) (string, error) {
+	return MarshalWith(obj, DefaultCompression)
 }
 
-func zip(in []byte) []byte {
-	var b bytes.Buffer
-	gz := gzip.NewWriter(&b)
-	_, err := gz.Write(in)
-	if err != nil {
-		panic(err)
-	}
-	err = gz.Flush()
+// MarshalWith is Marshal with an explicit Compression.
+// Compressed output is prefixed with its format so Unmarshal can detect it.
+func MarshalWith(obj interface{}, c Compression) (string, error) {
+	b, err := json.Marshal(obj)
 	if err != nil {
-		panic(err)
+		return "", &Error{Kind: ErrBadJSON, Err: err}
 	}
-	err = gz.Close()
-	if err != nil {
-		panic(err)
+
+	if b, err = compressBytes(c, b); err != nil {
+		return "", &Error{Kind: ErrBadCompression, Err: err}
 	}
-	return b.Bytes()
+
+	return compressionPrefixes[c] + base64.StdEncoding.EncodeToString(b), nil
 }
 
-func unzip(in []byte) []byte {
-	var b bytes.Buffer
-	_, err := b.Write(in)
+// Unmarshal is Decode that returns an *Error instead of panicking
+func Unmarshal(in string, obj interface{}) error {
+	c, in := splitCompression(strings.TrimSpace(in))
+
+	b, err := base64.StdEncoding.DecodeString(in)
 	if err != nil {
-		panic(err)
+		return &Error{Kind: ErrBadBase64, Err: err}
 	}
-	r, err := gzip.NewReader(&b)
-	if err != nil {
-		panic(err)
+
+	if b, err = decompressBytes(c, b); err != nil {
+		return &Error{Kind: ErrBadCompression, Err: err}
 	}
-	res, err := ioutil.ReadAll(r)
-	if err != nil {
-		panic(err)
+
+	if err = json.Unmarshal(b, obj); err != nil {
+		return &Error{Kind: ErrBadJSON, Err: err}
 	}
-	return res
+
+	return nil
 }
@@ -0,0 +1,111 @@
+package signal
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineReaderKeepsReadAhead(t *testing.T) {
+	blob := Encode(map[string]string{"type": "offer"})
+	lr := NewLineReader(strings.NewReader("first\n\n  second  \n" + strings.Join(Armor(blob, 16), "") + "last"))
+
+	for _, want := range []string{"first", "second"} {
+		if line, err := lr.ReadLine(context.Background()); err != nil || line != want {
+			t.Fatalf("ReadLine() = %q, %v, want %q", line, err, want)
+		}
+	}
+	if got, err := lr.ReadArmored(context.Background()); err != nil || got != blob {
+		t.Fatalf("ReadArmored() = %q, %v, want %q", got, err, blob)
+	}
+	if line, err := lr.ReadLine(context.Background()); err != nil || line != "last" {
+		t.Fatalf("ReadLine() = %q, %v, want %q", line, err, "last")
+	}
+	if _, err := lr.ReadLine(context.Background()); !errors.Is(err, io.EOF) {
+		t.Fatalf("ReadLine() at the end = %v, want io.EOF", err)
+	}
+}
+
+func TestReadLineCancelledKeepsLine(t *testing.T) {
+	pr, pw := io.Pipe()
+	t.Cleanup(func() { pr.Close() })
+	lr := NewLineReader(pr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := lr.ReadLine(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ReadLine() = %v, want context.DeadlineExceeded", err)
+	}
+
+	// The read of the cancelled call takes the line, the next call gets it
+	go func() {
+		_, _ = io.WriteString(pw, "answer\n")
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if line, err := lr.ReadLine(ctx); err != nil || line != "answer" {
+		t.Fatalf("ReadLine() = %q, %v, want %q", line, err, "answer")
+	}
+}
+
+func TestReadLineConcurrent(t *testing.T) {
+	pr, pw := io.Pipe()
+	t.Cleanup(func() { pr.Close() })
+	lr := NewLineReader(pr)
+
+	lines := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			line, err := lr.ReadLine(context.Background())
+			if err != nil {
+				line = err.Error()
+			}
+			lines <- line
+		}()
+	}
+	go func() {
+		_, _ = io.WriteString(pw, "one\ntwo\n")
+	}()
+
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case line := <-lines:
+			got[line] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("a call is still waiting, got %v", got)
+		}
+	}
+	if !got["one"] || !got["two"] {
+		t.Fatalf("got %v, want one and two", got)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     error
+	}{
+		{"base64", "not base64!", ErrBadBase64},
+		{"gzip", compressionPrefixes[CompressionGzip] + base64.StdEncoding.EncodeToString([]byte("not gzip")), ErrBadCompression},
+		{"zstd", compressionPrefixes[CompressionZstd] + base64.StdEncoding.EncodeToString([]byte("not zstd")), ErrBadCompression},
+		{"json", base64.StdEncoding.EncodeToString([]byte("{not json")), ErrBadJSON},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Unmarshal(test.in, &map[string]string{})
+			if !errors.Is(err, test.want) {
+				t.Fatalf("got %v, want %v", err, test.want)
+			}
+			var signalErr *Error
+			if !errors.As(err, &signalErr) || signalErr.Err == nil {
+				t.Fatalf("got %v, want an *Error with its cause", err)
+			}
+		})
+	}
+}
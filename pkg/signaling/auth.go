@@ -0,0 +1,255 @@
+package signaling
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"webrtc-demo/pkg/config"
+
+	"github.com/livekit/protocol/auth"
+)
+
+// HMACScheme is the Authorization scheme of HMAC signed requests:
+//
+//	Authorization: HMAC-SHA256 key=<api key>, ts=<unix seconds>, sig=<base64 signature>
+//
+// The signature is HMAC-SHA256 keyed by the api secret over
+// "<method>\n<request uri>\n<ts>\n<hex sha256 of the body>", the request uri is
+// the path with its query, e.g. /sessions/abc/sdp?trickle=true.
+const HMACScheme = "HMAC-SHA256"
+
+// hmacMaxSkew is how far the timestamp of a signed request may be off
+const hmacMaxSkew = 30 * time.Second
+
+var (
+	errNoCredentials = errors.New("missing credentials")
+	errBadScheme     = errors.New("unsupported authorization scheme")
+	errUnknownKey    = errors.New("unknown api key")
+	errBadSignature  = errors.New("bad signature")
+	errStale         = errors.New("stale or future timestamp")
+	errNoRoomJoin    = errors.New("token does not grant roomJoin")
+	errWrongRoom     = errors.New("token is for another room")
+)
+
+// Authenticator guards signaling handlers with the ApiKey/ApiSecret of a config.Config.
+//
+// Requests are accepted either HMAC signed (see HMACScheme) or with a bearer
+// JWT issued for the key, e.g. a LiveKit access token. Missing or invalid
+// credentials get 401, a valid token without roomJoin for RoomName gets 403.
+type Authenticator struct {
+	APIKey    string
+	APISecret string
+	// RoomName is the room bearer tokens must grant, any room when empty
+	RoomName string
+}
+
+// NewAuthenticator creates an Authenticator from cfg, it returns nil unless cfg.Authenticated
+func NewAuthenticator(cfg config.Config) *Authenticator {
+	if !cfg.Authenticated() {
+		return nil
+	}
+	return &Authenticator{APIKey: cfg.ApiKey, APISecret: cfg.ApiSecret, RoomName: cfg.RoomName}
+}
+
+// Middleware rejects unauthenticated requests before they reach next.
+// A nil Authenticator lets everything through so authentication stays optional.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, err := a.authenticate(r)
+		if err != nil {
+			if status == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer, %s`, HMACScheme))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (int, error) {
+	header := r.Header.Get("Authorization")
+	scheme, credentials, _ := strings.Cut(header, " ")
+
+	switch {
+	case header == "":
+		return http.StatusUnauthorized, errNoCredentials
+	case strings.EqualFold(scheme, "Bearer"):
+		return a.verifyToken(strings.TrimSpace(credentials))
+	case strings.EqualFold(scheme, HMACScheme):
+		return a.verifySignature(r, credentials)
+	}
+	return http.StatusUnauthorized, errBadScheme
+}
+
+func (a *Authenticator) verifyToken(token string) (int, error) {
+	verifier, err := auth.ParseAPIToken(token)
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+	if verifier.APIKey() != a.APIKey {
+		return http.StatusUnauthorized, errUnknownKey
+	}
+
+	grants, err := verifier.Verify(a.APISecret)
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+
+	if grants.Video == nil || !grants.Video.RoomJoin {
+		return http.StatusForbidden, errNoRoomJoin
+	}
+	if a.RoomName != "" && grants.Video.Room != a.RoomName {
+		return http.StatusForbidden, errWrongRoom
+	}
+	return http.StatusOK, nil
+}
+
+func (a *Authenticator) verifySignature(r *http.Request, credentials string) (int, error) {
+	params := map[string]string{}
+	for _, field := range strings.Split(credentials, ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(field), "="); ok {
+			params[k] = v
+		}
+	}
+
+	if params["key"] != a.APIKey {
+		return http.StatusUnauthorized, errUnknownKey
+	}
+
+	ts, err := strconv.ParseInt(params["ts"], 10, 64)
+	if err != nil {
+		return http.StatusUnauthorized, errStale
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > hmacMaxSkew || skew < -hmacMaxSkew {
+		return http.StatusUnauthorized, errStale
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(params["sig"])
+	if err != nil {
+		return http.StatusUnauthorized, errBadSignature
+	}
+
+	body, err := readAndRestoreBody(r)
+	if err != nil {
+		return BodyErrorStatus(err), err
+	}
+
+	if !hmac.Equal(sig, signature(a.APISecret, r.Method, r.URL.RequestURI(), params["ts"], body)) {
+		return http.StatusUnauthorized, errBadSignature
+	}
+	return http.StatusOK, nil
+}
+
+// SignRequest adds a HMACScheme Authorization header to req
+func SignRequest(req *http.Request, apiKey, apiSecret string) error {
+	body, err := readAndRestoreBody(req)
+	if err != nil {
+		return err
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	sig := signature(apiSecret, req.Method, req.URL.RequestURI(), ts, body)
+	req.Header.Set("Authorization", fmt.Sprintf("%s key=%s, ts=%s, sig=%s",
+		HMACScheme, apiKey, ts, base64.StdEncoding.EncodeToString(sig)))
+
+	return nil
+}
+
+// NewToken issues a bearer JWT for cfg that an Authenticator built from the same config accepts
+func NewToken(cfg config.Config, validFor time.Duration) (string, error) {
+	return auth.NewAccessToken(cfg.ApiKey, cfg.ApiSecret).
+		SetIdentity(cfg.Identity).
+		SetValidFor(validFor).
+		AddGrant(&auth.VideoGrant{RoomJoin: true, Room: cfg.RoomName}).
+		ToJWT()
+}
+
+// AuthHeader returns the Authorization header for a request that has no body,
+// e.g. a WebSocket handshake. It is empty when cfg has no credentials.
+func AuthHeader(cfg config.Config, method, url string) (http.Header, error) {
+	req, err := http.NewRequest(method, url, nil) // nolint:noctx
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &AuthTransport{Config: cfg}
+	if err := transport.authorize(req); err != nil {
+		return nil, err
+	}
+
+	return req.Header, nil
+}
+
+// AuthTransport authenticates every outgoing request with the credentials of Config.
+// A static Token is sent as bearer JWT, otherwise requests are HMAC signed.
+type AuthTransport struct {
+	Config config.Config
+	// Base is the underlying transport, http.DefaultTransport when nil
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *AuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if err := t.authorize(req); err != nil {
+		return nil, err
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+func (t *AuthTransport) authorize(req *http.Request) error {
+	switch {
+	case t.Config.Token != "":
+		req.Header.Set("Authorization", "Bearer "+t.Config.Token)
+	case t.Config.Authenticated():
+		return SignRequest(req, t.Config.ApiKey, t.Config.ApiSecret)
+	}
+	return nil
+}
+
+func signature(secret, method, requestURI, ts string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + ts + "\n" + hex.EncodeToString(bodyHash[:])))
+	return mac.Sum(nil)
+}
+
+// readAndRestoreBody reads the whole body and puts it back for the next reader,
+// Limits.Middleware bounds it on the server
+func readAndRestoreBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Body.Close(); err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
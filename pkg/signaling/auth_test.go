@@ -0,0 +1,163 @@
+package signaling
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"webrtc-demo/pkg/config"
+
+	"github.com/livekit/protocol/auth"
+)
+
+var testAuthConfig = config.Config{ApiKey: "key", ApiSecret: "secret", RoomName: "stark-tower", Identity: "tony"}
+
+// echoHandler answers with the body of the request, so tests see it reached next intact
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.Copy(w, r.Body)
+})
+
+// authRequest sends a POST with body through the Middleware of a, after authorize
+// set its Authorization header, and returns the recorded response
+func authRequest(a *Authenticator, body string, authorize func(*http.Request) error) (*httptest.ResponseRecorder, error) {
+	r := httptest.NewRequest(http.MethodPost, "/signal", strings.NewReader(body))
+	if err := authorize(r); err != nil {
+		return nil, err
+	}
+	w := httptest.NewRecorder()
+	a.Middleware(echoHandler).ServeHTTP(w, r)
+	return w, nil
+}
+
+func TestAuthenticator(t *testing.T) {
+	otherRoom := testAuthConfig
+	otherRoom.RoomName = "avengers"
+	staleTS := strconv.FormatInt(time.Now().Add(-2*hmacMaxSkew).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		authorize func(*http.Request) error
+		want      int
+	}{
+		{"no credentials", func(*http.Request) error { return nil }, http.StatusUnauthorized},
+		{"unknown scheme", func(r *http.Request) error {
+			r.SetBasicAuth("key", "secret")
+			return nil
+		}, http.StatusUnauthorized},
+		{"signed", func(r *http.Request) error {
+			return SignRequest(r, "key", "secret")
+		}, http.StatusOK},
+		{"signed with another secret", func(r *http.Request) error {
+			return SignRequest(r, "key", "guess")
+		}, http.StatusUnauthorized},
+		{"signed, then the body changed", func(r *http.Request) error {
+			if err := SignRequest(r, "key", "secret"); err != nil {
+				return err
+			}
+			r.Body = io.NopCloser(strings.NewReader("forged"))
+			return nil
+		}, http.StatusUnauthorized},
+		{"signed with a query", func(r *http.Request) error {
+			r.URL.RawQuery = "trickle=true"
+			return SignRequest(r, "key", "secret")
+		}, http.StatusOK},
+		{"signed, then the query changed", func(r *http.Request) error {
+			r.URL.RawQuery = "trickle=true"
+			if err := SignRequest(r, "key", "secret"); err != nil {
+				return err
+			}
+			r.URL.RawQuery = "trickle=false"
+			return nil
+		}, http.StatusUnauthorized},
+		{"stale signature", func(r *http.Request) error {
+			body, err := readAndRestoreBody(r)
+			sig := signature("secret", r.Method, r.URL.RequestURI(), staleTS, body)
+			r.Header.Set("Authorization", fmt.Sprintf("%s key=key, ts=%s, sig=%s", HMACScheme, staleTS, base64.StdEncoding.EncodeToString(sig)))
+			return err
+		}, http.StatusUnauthorized},
+		{"token", func(r *http.Request) error {
+			token, err := NewToken(testAuthConfig, time.Minute)
+			r.Header.Set("Authorization", "Bearer "+token)
+			return err
+		}, http.StatusOK},
+		{"token for another room", func(r *http.Request) error {
+			token, err := NewToken(otherRoom, time.Minute)
+			r.Header.Set("Authorization", "Bearer "+token)
+			return err
+		}, http.StatusForbidden},
+		{"token without roomJoin", func(r *http.Request) error {
+			token, err := auth.NewAccessToken("key", "secret").SetIdentity("tony").SetValidFor(time.Minute).ToJWT()
+			r.Header.Set("Authorization", "Bearer "+token)
+			return err
+		}, http.StatusForbidden},
+		{"token of another key", func(r *http.Request) error {
+			token, err := auth.NewAccessToken("other", "secret").AddGrant(&auth.VideoGrant{RoomJoin: true}).ToJWT()
+			r.Header.Set("Authorization", "Bearer "+token)
+			return err
+		}, http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w, err := authRequest(NewAuthenticator(testAuthConfig), "offer", test.authorize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != test.want {
+				t.Fatalf("status %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), test.want)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+			if w.Code == http.StatusOK && w.Body.String() != "offer" {
+				t.Errorf("next got body %q, want offer", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthenticatorOptional(t *testing.T) {
+	if a := NewAuthenticator(config.Config{}); a != nil {
+		t.Fatal("an Authenticator without api key")
+	}
+	w, err := authRequest(nil, "offer", func(*http.Request) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want %d without an Authenticator", w.Code, http.StatusOK)
+	}
+}
+
+func TestAuthTransport(t *testing.T) {
+	server := httptest.NewServer(NewAuthenticator(testAuthConfig).Middleware(echoHandler))
+	t.Cleanup(server.Close)
+
+	for name, cfg := range map[string]config.Config{"signed": testAuthConfig, "token": {Token: mustToken(t)}} {
+		client := &http.Client{Transport: &AuthTransport{Config: cfg}}
+		resp, err := client.Post(server.URL+"/signal", "text/plain", strings.NewReader("offer"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != "offer" {
+			t.Errorf("%s: status %d and body %q, want %d and offer", name, resp.StatusCode, body, http.StatusOK)
+		}
+	}
+}
+
+func mustToken(t *testing.T) string {
+	t.Helper()
+
+	token, err := NewToken(testAuthConfig, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
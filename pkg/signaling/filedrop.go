@@ -0,0 +1,180 @@
+package signaling
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"webrtc-demo/pkg/signal"
+)
+
+const (
+	// fileDropPollInterval is how often the inbox is listed, polling also works on NFS
+	// where change notifications of other hosts do not arrive
+	fileDropPollInterval = 250 * time.Millisecond
+
+	fileDropExt = ".signal"
+)
+
+// FileDropConn exchanges Messages as files in a directory both peers can reach,
+// e.g. an NFS share or a bind mount, so they never open a port to each other.
+//
+// A message for the peer playing role is a pkg/signal blob in
+//
+//	<dir>/<session>/<role>/<sequence>-<type>.signal
+//
+// written to a hidden temporary file first and renamed into place, so a reader
+// never sees it half written. The reader polls its inbox and removes every
+// message it consumed.
+type FileDropConn struct {
+	inbox, outbox string
+	seq           int
+
+	writeMu sync.Mutex
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// DialFileDrop joins sessionID in dir as role. Messages the remote peer dropped
+// before are delivered first, leftovers of a previous run of role are removed.
+func DialFileDrop(dir, sessionID string, role Role) (*FileDropConn, error) {
+	var peer Role
+	switch role {
+	case RoleOffer:
+		peer = RoleAnswer
+	case RoleAnswer:
+		peer = RoleOffer
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownRole, role)
+	}
+
+	c := &FileDropConn{
+		inbox:  filepath.Join(dir, sessionID, string(role)),
+		outbox: filepath.Join(dir, sessionID, string(peer)),
+		closed: make(chan struct{}),
+	}
+
+	for _, d := range []string{c.inbox, c.outbox} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	stale, err := c.list(c.outbox)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range stale {
+		if err := os.Remove(filepath.Join(c.outbox, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Send drops a Message into the inbox of the remote peer
+func (c *FileDropConn) Send(msg Message) error {
+	blob, err := signal.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.seq++
+	name := fmt.Sprintf("%08d-%s%s", c.seq, msg.Type, fileDropExt)
+
+	tmp, err := os.CreateTemp(c.outbox, ".drop-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(blob + "\n"); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	// Flush to the share before the rename makes the message visible
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(c.outbox, name))
+}
+
+// Recv blocks until the remote peer dropped the next Message and consumes it
+func (c *FileDropConn) Recv() (Message, error) {
+	for {
+		names, err := c.list(c.inbox)
+		if err != nil {
+			return Message{}, err
+		}
+
+		if len(names) > 0 {
+			return c.consume(filepath.Join(c.inbox, names[0]))
+		}
+
+		select {
+		case <-time.After(fileDropPollInterval):
+		case <-c.closed:
+			return Message{}, io.EOF
+		}
+	}
+}
+
+func (c *FileDropConn) consume(path string) (Message, error) {
+	msg := Message{}
+
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return msg, err
+	}
+	if err := os.Remove(path); err != nil {
+		return msg, err
+	}
+
+	err = signal.Unmarshal(string(blob), &msg)
+	return msg, err
+}
+
+// list returns the messages in dir in the order they were sent
+func (c *FileDropConn) list(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") && strings.HasSuffix(e.Name(), fileDropExt) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Close says bye to the remote peer and stops Recv
+func (c *FileDropConn) Close() error {
+	err := error(nil)
+	c.closeOnce.Do(func() {
+		err = c.Send(Message{Type: MessageTypeBye})
+		close(c.closed)
+	})
+	return err
+}
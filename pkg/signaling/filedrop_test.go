@@ -0,0 +1,81 @@
+package signaling
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func dialTestFileDrop(t *testing.T, dir string, role Role) *FileDropConn {
+	t.Helper()
+
+	conn, err := DialFileDrop(dir, "session", role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestFileDropConn(t *testing.T) {
+	dir := t.TempDir()
+	offer := dialTestFileDrop(t, dir, RoleOffer)
+
+	// The answer side joins late, what the offer side dropped meanwhile comes first and in order
+	for _, c := range []string{"one", "two"} {
+		if err := offer.Send(candidateMessage(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	answer := dialTestFileDrop(t, dir, RoleAnswer)
+	for _, c := range []string{"one", "two"} {
+		recvCandidate(t, answer, c)
+	}
+
+	// Consumed messages are removed, no temporary file is left behind
+	entries, err := os.ReadDir(filepath.Join(dir, "session", string(RoleAnswer)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("inbox holds %d files after Recv, want none", len(entries))
+	}
+
+	if err := answer.Send(candidateMessage("three")); err != nil {
+		t.Fatal(err)
+	}
+	recvCandidate(t, offer, "three")
+
+	if err := answer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := offer.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != MessageTypeBye {
+		t.Fatalf("got %s after Close, want bye", msg.Type)
+	}
+}
+
+func TestFileDropRemovesLeftovers(t *testing.T) {
+	dir := t.TempDir()
+
+	// A previous run of the offer side dropped a message nobody read
+	if err := dialTestFileDrop(t, dir, RoleOffer).Send(candidateMessage("stale")); err != nil {
+		t.Fatal(err)
+	}
+	offer := dialTestFileDrop(t, dir, RoleOffer)
+	answer := dialTestFileDrop(t, dir, RoleAnswer)
+
+	if err := offer.Send(candidateMessage("fresh")); err != nil {
+		t.Fatal(err)
+	}
+	recvCandidate(t, answer, "fresh")
+}
+
+func TestFileDropUnknownRole(t *testing.T) {
+	if _, err := DialFileDrop(t.TempDir(), "session", Role("viewer")); !errors.Is(err, errUnknownRole) {
+		t.Fatalf("got %v, want %v", err, errUnknownRole)
+	}
+}
@@ -0,0 +1,252 @@
+package signaling
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signaling/signalingpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCNegotiateMethod is the full name of the Negotiate RPC of signaling.proto
+const GRPCNegotiateMethod = signalingpb.Signaling_Negotiate_FullMethodName
+
+// grpcCloseTimeout is how long Close waits for the server to end the stream after bye
+const grpcCloseTimeout = time.Second
+
+// grpcSignaling hands every Negotiate stream to onConn as a GRPCConn
+type grpcSignaling struct {
+	signalingpb.UnimplementedSignalingServer
+
+	onConn func(*GRPCConn)
+}
+
+func (s grpcSignaling) Negotiate(stream signalingpb.Signaling_NegotiateServer) error {
+	conn := &GRPCConn{stream: stream, closed: make(chan struct{})}
+	s.onConn(conn)
+
+	// The stream lives until the server side is closed or the client went away
+	select {
+	case <-conn.closed:
+	case <-stream.Context().Done():
+	}
+	return nil
+}
+
+// RegisterGRPCSignaling serves the Signaling service on server, every Negotiate
+// stream is handed to onConn. GRPCServerOptions guard the server.
+func RegisterGRPCSignaling(server *grpc.Server, onConn func(*GRPCConn)) {
+	signalingpb.RegisterSignalingServer(server, grpcSignaling{onConn: onConn})
+}
+
+// GRPCServerOptions check streams with authenticator, a nil authenticator accepts everyone, and bound them by DefaultLimits.
+// The server speaks TLS when tlsConfig is not nil, see ServerTLSConfig.
+func GRPCServerOptions(authenticator *Authenticator, tlsConfig *tls.Config) []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.StreamInterceptor(grpcGuard(authenticator, NewRateLimiter(DefaultLimits.RequestsPerSecond, DefaultLimits.Burst))),
+	}
+	if DefaultLimits.MaxBodyBytes > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(int(DefaultLimits.MaxBodyBytes)))
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	return opts
+}
+
+// StartGRPCServer serves the Signaling service on addr and returns accepted connections.
+// Streams are checked by authenticator, a nil authenticator accepts everyone.
+func StartGRPCServer(addr string, authenticator *Authenticator, tlsConfig *tls.Config) chan *GRPCConn {
+	connChan := make(chan *GRPCConn, 1)
+
+	server := grpc.NewServer(GRPCServerOptions(authenticator, tlsConfig)...)
+	RegisterGRPCSignaling(server, func(c *GRPCConn) {
+		connChan <- c
+	})
+
+	go func() {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := server.Serve(listener); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	return connChan
+}
+
+// grpcGuard rate limits new streams per client IP and authenticates them like
+// HTTP requests to the path of their method, the Authorization travels as metadata
+func grpcGuard(authenticator *Authenticator, clients *RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if p, ok := peer.FromContext(ss.Context()); ok {
+			host, _, err := net.SplitHostPort(p.Addr.String())
+			if err != nil {
+				host = p.Addr.String()
+			}
+			if !clients.Allow(host) {
+				return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+			}
+		}
+
+		if authenticator != nil {
+			r := &http.Request{Method: http.MethodPost, URL: &url.URL{Path: info.FullMethod}, Header: http.Header{}}
+			md, _ := metadata.FromIncomingContext(ss.Context())
+			for _, v := range md.Get("authorization") {
+				r.Header.Add("Authorization", v)
+			}
+
+			code, err := authenticator.authenticate(r)
+			switch {
+			case code == http.StatusForbidden:
+				return status.Error(codes.PermissionDenied, err.Error())
+			case err != nil:
+				return status.Error(codes.Unauthenticated, err.Error())
+			}
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// grpcCredentials sends the Authorization of an AuthTransport as metadata
+type grpcCredentials struct {
+	cfg config.Config
+}
+
+func (c grpcCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	// The server verifies the signature against the path of the method
+	header, err := AuthHeader(c.cfg, http.MethodPost, "grpc://signaling"+GRPCNegotiateMethod)
+	if err != nil {
+		return nil, err
+	}
+	if authorization := header.Get("Authorization"); authorization != "" {
+		return map[string]string{"authorization": authorization}, nil
+	}
+	return nil, nil
+}
+
+func (c grpcCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// GRPCDialOptions trust the server as configured in cfg.TLS and authenticate
+// the stream with the credentials of cfg
+func GRPCDialOptions(cfg config.Config) ([]grpc.DialOption, error) {
+	transportCredentials := insecure.NewCredentials()
+	if cfg.TLS.Enabled() {
+		tlsConfig, err := ClientTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		transportCredentials = credentials.NewTLS(tlsConfig)
+	}
+
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithPerRPCCredentials(grpcCredentials{cfg: cfg}),
+	}, nil
+}
+
+// grpcStream is what a GRPCConn needs of either end of a Negotiate stream
+type grpcStream interface {
+	Context() context.Context
+	Send(*signalingpb.Signal) error
+	Recv() (*signalingpb.Signal, error)
+}
+
+// GRPCConn is one Negotiate stream carrying typed Messages in both directions
+type GRPCConn struct {
+	stream  grpcStream
+	writeMu sync.Mutex
+
+	// client side
+	cc     *grpc.ClientConn
+	cancel context.CancelFunc
+
+	// server side, closing ends the stream
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// DialGRPC opens a Negotiate stream to the gRPC signaling server at target,
+// e.g. localhost:60000. opts are usually GRPCDialOptions, an in-memory
+// listener like bufconn is reached with grpc.WithContextDialer.
+func DialGRPC(ctx context.Context, target string, opts ...grpc.DialOption) (*GRPCConn, error) {
+	cc, err := grpc.DialContext(ctx, target, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The stream outlives ctx, it ends with Close
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stream, err := signalingpb.NewSignalingClient(cc).Negotiate(streamCtx)
+	if err != nil {
+		cancel()
+		_ = cc.Close()
+		return nil, err
+	}
+
+	return &GRPCConn{stream: stream, cc: cc, cancel: cancel, closed: make(chan struct{})}, nil
+}
+
+// Send writes a Message to the stream, it is safe for concurrent use
+func (c *GRPCConn) Send(msg Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.stream.Send(toSignal(msg))
+}
+
+// Recv blocks until the next Message arrives, it returns io.EOF once the remote side ended the stream
+func (c *GRPCConn) Recv() (Message, error) {
+	signal, err := c.stream.Recv()
+	if err != nil {
+		return Message{}, err
+	}
+	return fromSignal(signal), nil
+}
+
+// Close says bye to the remote side and ends the stream
+func (c *GRPCConn) Close() error {
+	err := error(nil)
+	c.closeOnce.Do(func() {
+		_ = c.Send(Message{Type: MessageTypeBye})
+		close(c.closed)
+
+		if c.cc == nil {
+			return
+		}
+
+		if clientStream, ok := c.stream.(grpc.ClientStream); ok {
+			_ = clientStream.CloseSend()
+		}
+		// Give the bye time to arrive before the connection goes down
+		select {
+		case <-c.stream.Context().Done():
+		case <-time.After(grpcCloseTimeout):
+		}
+		c.cancel()
+		err = c.cc.Close()
+	})
+	return err
+}
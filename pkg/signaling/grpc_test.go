@@ -0,0 +1,120 @@
+package signaling
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialBufconn serves the Signaling service in memory and opens a Negotiate
+// stream to it, it returns both ends
+func dialBufconn(t *testing.T) (client, server *GRPCConn) {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer(GRPCServerOptions(nil, nil)...)
+	conns := make(chan *GRPCConn, 1)
+	RegisterGRPCSignaling(grpcServer, func(c *GRPCConn) {
+		conns <- c
+	})
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := DialGRPC(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case server = <-conns:
+	case <-ctx.Done():
+		t.Fatal("the server got no stream")
+	}
+	return client, server
+}
+
+func TestGRPCConnBufconn(t *testing.T) {
+	client, server := dialBufconn(t)
+
+	mid, index, ufrag := "", uint16(0), "ufrag"
+	sent := []Message{
+		NewSDPMessage(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "v=0\r\n"}),
+		// Empty optional fields are still set on the other side
+		NewCandidateMessage(webrtc.ICECandidateInit{Candidate: "candidate:1 1 udp 1 192.0.2.1 5000 typ host", SDPMid: &mid, SDPMLineIndex: &index, UsernameFragment: &ufrag}),
+		NewCandidateMessage(webrtc.ICECandidateInit{}),
+	}
+	for _, msg := range sent {
+		if err := client.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range sent {
+		got, err := server.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	}
+
+	if err := server.Send(NewStateMessage(webrtc.PeerConnectionStateConnected)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := got.PeerConnectionState(); state != webrtc.PeerConnectionStateConnected {
+		t.Fatalf("got state %s, want connected", state)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := server.Recv(); err != nil || got.Type != MessageTypeBye {
+		t.Fatalf("got %+v, %v after Close, want bye", got, err)
+	}
+}
+
+func TestGRPCSignalerState(t *testing.T) {
+	client, server := dialBufconn(t)
+	offer, answer := NewConnSignaler(client), NewConnSignaler(server)
+
+	pc := newTestPeerConnection(t)
+	n := NewNegotiator(pc, answer, true)
+	states := make(chan webrtc.PeerConnectionState, 1)
+	n.OnRemoteState(func(s webrtc.PeerConnectionState) {
+		states <- s
+	})
+	runNegotiator(t, n)
+
+	if err := offer.SendState(webrtc.PeerConnectionStateFailed); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case s := <-states:
+		if s != webrtc.PeerConnectionStateFailed {
+			t.Fatalf("got state %s, want failed", s)
+		}
+	case <-time.After(connectTimeout):
+		t.Fatal("timed out waiting for the remote state")
+	}
+	if err := offer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+package signaling
+
+import (
+	"webrtc-demo/pkg/signaling/signalingpb"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// toSignal converts a Message to the Signal of signaling.proto
+func toSignal(msg Message) *signalingpb.Signal {
+	signal := &signalingpb.Signal{Type: string(msg.Type), State: msg.State}
+	if msg.SDP != nil {
+		signal.Sdp = &signalingpb.SessionDescription{Type: msg.SDP.Type.String(), Sdp: msg.SDP.SDP}
+	}
+	if c := msg.Candidate; c != nil {
+		signal.Candidate = &signalingpb.ICECandidate{
+			Candidate:        c.Candidate,
+			SdpMid:           c.SDPMid,
+			UsernameFragment: c.UsernameFragment,
+		}
+		if c.SDPMLineIndex != nil {
+			index := uint32(*c.SDPMLineIndex)
+			signal.Candidate.SdpMlineIndex = &index
+		}
+	}
+	return signal
+}
+
+// fromSignal converts the Signal of signaling.proto to a Message
+func fromSignal(signal *signalingpb.Signal) Message {
+	msg := Message{Type: MessageType(signal.GetType()), State: signal.GetState()}
+	if sdp := signal.GetSdp(); sdp != nil {
+		msg.SDP = &webrtc.SessionDescription{Type: webrtc.NewSDPType(sdp.GetType()), SDP: sdp.GetSdp()}
+	}
+	if c := signal.GetCandidate(); c != nil {
+		msg.Candidate = &webrtc.ICECandidateInit{
+			Candidate:        c.GetCandidate(),
+			SDPMid:           c.SdpMid,
+			UsernameFragment: c.UsernameFragment,
+		}
+		if c.SdpMlineIndex != nil {
+			index := uint16(*c.SdpMlineIndex)
+			msg.Candidate.SDPMLineIndex = &index
+		}
+	}
+	return msg
+}
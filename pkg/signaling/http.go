@@ -9,12 +9,23 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
+// StartHttpSdpServer serves GET /sdp on addr and pushes every request body into the returned channel.
+//
+// There is a single channel for all callers, use SessionServer when more than one offerer may connect.
+//
+// Deprecated: many HTTP clients and proxies drop the body of a GET and the response
+// is always "done". Use SessionServer, it takes the offer with POST, returns the
+// answer and streams its candidates as Server-Sent Events.
 func StartHttpSdpServer(addr string) chan string {
 	sdpChan := make(chan string)
 
 	router := httprouter.New()
 	router.GET("/sdp", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		body, _ := io.ReadAll(r.Body)
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, DefaultLimits.MaxBodyBytes))
+		if err != nil {
+			http.Error(w, err.Error(), BodyErrorStatus(err))
+			return
+		}
 		fmt.Fprintf(w, "done")
 		sdpChan <- string(body)
 	})
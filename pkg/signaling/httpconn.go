@@ -0,0 +1,156 @@
+package signaling
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPConnPath is where an HTTPConn receives the messages of its remote peer
+const HTTPConnPath = "/signal"
+
+const (
+	// httpConnRetries and httpConnRetryInterval give the remote peer time to start listening
+	httpConnRetries       = 30
+	httpConnRetryInterval = 500 * time.Millisecond
+
+	httpConnShutdownTimeout = time.Second
+)
+
+// HTTPConn exchanges Messages between two peers that can reach each other's HTTP port.
+// Every peer serves POST HTTPConnPath and posts its own messages as JSON to the
+// HTTPConnPath of the remote peer with its own http.Client.
+type HTTPConn struct {
+	remoteURL string
+	client    *http.Client
+	server    *http.Server
+
+	inbox     chan Message
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// ListenHTTPConn serves an HTTPConn on addr, over TLS when tlsConfig is not nil,
+// and sends to remoteURL, e.g. http://localhost:60000/signal, with client.
+// Requests are checked by authenticator, a nil authenticator accepts everyone,
+// and bounded by DefaultLimits.
+func ListenHTTPConn(addr, remoteURL string, client *http.Client, authenticator *Authenticator, tlsConfig *tls.Config) (*HTTPConn, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	c := &HTTPConn{
+		remoteURL: remoteURL,
+		client:    client,
+		inbox:     make(chan Message, 16),
+		closed:    make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(HTTPConnPath, DefaultLimits.Middleware(authenticator.Middleware(http.HandlerFunc(c.handleMessage))))
+	c.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := c.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println("signaling server stopped:", err)
+		}
+	}()
+
+	return c, nil
+}
+
+func (c *HTTPConn) handleMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	msg := Message{}
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, err.Error(), BodyErrorStatus(err))
+		return
+	}
+
+	select {
+	case c.inbox <- msg:
+		w.WriteHeader(http.StatusNoContent)
+	case <-c.closed:
+		http.Error(w, "signaling closed", http.StatusGone)
+	}
+}
+
+// Send posts a Message to the remote peer, retrying while it does not listen yet
+func (c *HTTPConn) Send(msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = c.post(payload)
+
+		var netErr *net.OpError
+		if err == nil || !errors.As(err, &netErr) || attempt == httpConnRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(httpConnRetryInterval):
+		case <-c.closed:
+			return err
+		}
+	}
+}
+
+func (c *HTTPConn) post(payload []byte) error {
+	resp, err := c.client.Post(c.remoteURL, "application/json; charset=utf-8", bytes.NewReader(payload)) // nolint:noctx
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("message rejected: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// Recv blocks until the remote peer posted the next Message
+func (c *HTTPConn) Recv() (Message, error) {
+	select {
+	case msg := <-c.inbox:
+		return msg, nil
+	case <-c.closed:
+		return Message{}, io.EOF
+	}
+}
+
+// Close says bye to the remote peer and stops serving
+func (c *HTTPConn) Close() error {
+	err := error(nil)
+	c.closeOnce.Do(func() {
+		if payload, mErr := json.Marshal(Message{Type: MessageTypeBye}); mErr == nil {
+			_ = c.post(payload)
+		}
+		close(c.closed)
+
+		ctx, cancel := context.WithTimeout(context.Background(), httpConnShutdownTimeout)
+		defer cancel()
+		err = c.server.Shutdown(ctx)
+	})
+	return err
+}
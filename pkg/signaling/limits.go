@@ -0,0 +1,163 @@
+package signaling
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"webrtc-demo/pkg/signal"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdle is how long a key keeps its bucket without requests
+const rateLimiterIdle = 10 * time.Minute
+
+// Limits bounds what a single client can make a signaling server do.
+// Zero fields disable their limit.
+type Limits struct {
+	// MaxBodyBytes caps every request body, larger bodies get 413
+	MaxBodyBytes int64
+	// RequestsPerSecond and Burst rate limit each client IP, excess requests get 429
+	RequestsPerSecond float64
+	Burst             int
+	// SessionRequestsPerSecond and SessionBurst rate limit each session of a
+	// SessionServer, whatever IPs its requests come from
+	SessionRequestsPerSecond float64
+	SessionBurst             int
+	// MaxCandidatesPerSession caps the remote candidates of a session, further ones get 429
+	MaxCandidatesPerSession int
+	// MaxSessions caps the live sessions of a server, offers for more get 503
+	MaxSessions int
+	// ConnectTimeout closes sessions that did not connect within it, e.g. of
+	// offerers that went away before sending a candidate
+	ConnectTimeout time.Duration
+}
+
+// DefaultLimits leave plenty of room for a browser or Pion peer trickling its
+// candidates, an SDP is a few KiB
+var DefaultLimits = Limits{
+	MaxBodyBytes:             signal.MaxSDPBytes,
+	RequestsPerSecond:        20,
+	Burst:                    50,
+	SessionRequestsPerSecond: 10,
+	SessionBurst:             30,
+	MaxCandidatesPerSession:  100,
+	MaxSessions:              256,
+	ConnectTimeout:           30 * time.Second,
+}
+
+// ErrTooManySessions rejects a session over Limits.MaxSessions
+var ErrTooManySessions = errors.New("too many sessions")
+
+// Middleware rate limits every client IP and caps request bodies before next.
+// Wrap it around Authenticator.Middleware, which reads the body of signed requests.
+func (l Limits) Middleware(next http.Handler) http.Handler {
+	clients := NewRateLimiter(l.RequestsPerSecond, l.Burst)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !clients.Allow(clientIP(r)) {
+			TooManyRequests(w, "rate limit exceeded")
+			return
+		}
+		if l.MaxBodyBytes > 0 && r.ContentLength > l.MaxBodyBytes {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		LimitBody(w, r, l.MaxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimiter keeps a token bucket per key, e.g. a client IP or a session ID.
+// Buckets of keys that were idle for a while are dropped.
+// A nil RateLimiter allows everything.
+type RateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+}
+
+type rateBucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// NewRateLimiter allows perSecond requests per key with bursts of burst,
+// it returns nil when perSecond is not positive
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		limit:   rate.Limit(perSecond),
+		burst:   max(burst, 1),
+		buckets: map[string]*rateBucket{},
+	}
+}
+
+// Allow takes a token of key and reports whether there was one
+func (l *RateLimiter) Allow(key string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > rateLimiterIdle {
+		for k, b := range l.buckets {
+			if now.Sub(b.seen) > rateLimiterIdle {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateBucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.seen = now
+
+	return b.limiter.AllowN(now, 1)
+}
+
+// BodyErrorStatus returns the status for an error reading or decoding a request
+// body: 413 when it hit the limit of http.MaxBytesReader, 400 otherwise
+func BodyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// TooManyRequests writes a 429 that asks the client to retry in a second
+func TooManyRequests(w http.ResponseWriter, reason string) {
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, reason, http.StatusTooManyRequests)
+}
+
+// LimitBody caps the body of r at maxBytes, a limit that is not positive leaves it alone
+func LimitBody(w http.ResponseWriter, r *http.Request, maxBytes int64) {
+	if maxBytes > 0 && r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	}
+}
+
+// clientIP is the host of the remote address, proxy headers are not trusted
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,84 @@
+package signaling
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLimitsMiddleware(t *testing.T) {
+	limits := Limits{MaxBodyBytes: 16, RequestsPerSecond: 0.001, Burst: 2}
+	handler := limits.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), BodyErrorStatus(err))
+		}
+	}))
+
+	post := func(remoteAddr string, body io.Reader) *httptest.ResponseRecorder {
+		t.Helper()
+
+		r := httptest.NewRequest(http.MethodPost, "/signal", body)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := post("192.0.2.1:1000", strings.NewReader("small")); w.Code != http.StatusOK {
+		t.Fatalf("got %d, want %d", w.Code, http.StatusOK)
+	}
+	// The body is capped whether or not its length is known up front
+	if w := post("192.0.2.2:1000", strings.NewReader(strings.Repeat("a", 17))); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("got %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	if w := post("192.0.2.2:1000", io.MultiReader(strings.NewReader(strings.Repeat("a", 17)))); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("unknown length: got %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	// The burst of 192.0.2.1 is used up on its other port, other IPs are not affected
+	post("192.0.2.1:2000", nil)
+	w := post("192.0.2.1:3000", nil)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("got %d, want %d with Retry-After", w.Code, http.StatusTooManyRequests)
+	}
+	if w := post("192.0.2.3:1000", nil); w.Code != http.StatusOK {
+		t.Fatalf("other client: got %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	unlimited := NewRateLimiter(0, 10)
+	if unlimited != nil {
+		t.Fatal("got a RateLimiter without a rate")
+	}
+	for i := 0; i < 100; i++ {
+		if !unlimited.Allow("session") {
+			t.Fatal("a nil RateLimiter denied a request")
+		}
+	}
+
+	limiter := NewRateLimiter(0.001, 3)
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("a") {
+			t.Fatalf("request %d of the burst denied", i)
+		}
+	}
+	if limiter.Allow("a") {
+		t.Fatal("request beyond the burst allowed")
+	}
+	if !limiter.Allow("b") {
+		t.Fatal("another key shares the bucket")
+	}
+}
+
+func TestBodyErrorStatus(t *testing.T) {
+	if got := BodyErrorStatus(&http.MaxBytesError{Limit: 1}); got != http.StatusRequestEntityTooLarge {
+		t.Fatalf("got %d, want %d", got, http.StatusRequestEntityTooLarge)
+	}
+	if got := BodyErrorStatus(errors.New("bad json")); got != http.StatusBadRequest {
+		t.Fatalf("got %d, want %d", got, http.StatusBadRequest)
+	}
+}
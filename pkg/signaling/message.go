@@ -0,0 +1,70 @@
+package signaling
+
+import "github.com/pion/webrtc/v3"
+
+// MessageType identifies the payload carried by a Message
+type MessageType string
+
+const (
+	MessageTypeOffer     MessageType = "offer"
+	MessageTypeAnswer    MessageType = "answer"
+	MessageTypeCandidate MessageType = "candidate"
+	MessageTypeBye       MessageType = "bye"
+	MessageTypeState     MessageType = "state"
+)
+
+// Message is a single signaling message exchanged between two peers.
+// Offers and answers carry SDP, candidates carry Candidate, state updates carry
+// the connection State of the sender and bye carries nothing.
+type Message struct {
+	Type      MessageType                `json:"type"`
+	SDP       *webrtc.SessionDescription `json:"sdp,omitempty"`
+	Candidate *webrtc.ICECandidateInit   `json:"candidate,omitempty"`
+	State     string                     `json:"state,omitempty"`
+}
+
+// NewSDPMessage wraps an offer or an answer into a Message
+func NewSDPMessage(sdp webrtc.SessionDescription) Message {
+	msgType := MessageTypeAnswer
+	if sdp.Type == webrtc.SDPTypeOffer {
+		msgType = MessageTypeOffer
+	}
+	return Message{Type: msgType, SDP: &sdp}
+}
+
+// NewCandidateMessage wraps an ICE candidate into a Message
+func NewCandidateMessage(c webrtc.ICECandidateInit) Message {
+	return Message{Type: MessageTypeCandidate, Candidate: &c}
+}
+
+// NewStateMessage tells the remote peer the connection state of the sender
+func NewStateMessage(state webrtc.PeerConnectionState) Message {
+	return Message{Type: MessageTypeState, State: state.String()}
+}
+
+// PeerConnectionState returns the State of a state message, the zero state, "unknown",
+// for any other
+func (m Message) PeerConnectionState() webrtc.PeerConnectionState {
+	for state := webrtc.PeerConnectionStateNew; state <= webrtc.PeerConnectionStateClosed; state++ {
+		if m.Type == MessageTypeState && m.State == state.String() {
+			return state
+		}
+	}
+	return webrtc.PeerConnectionState(0)
+}
+
+// Validate reports a message that lacks the payload of its type, e.g. an offer
+// without SDP, which a remote peer may send
+func (m Message) Validate() error {
+	switch m.Type {
+	case MessageTypeOffer, MessageTypeAnswer:
+		if m.SDP == nil {
+			return errNoSDP
+		}
+	case MessageTypeCandidate:
+		if m.Candidate == nil {
+			return errNoCandidate
+		}
+	}
+	return nil
+}
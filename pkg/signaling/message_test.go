@@ -0,0 +1,30 @@
+package signaling
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func TestMessageValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want error
+	}{
+		{"offer", NewSDPMessage(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "v=0"}), nil},
+		{"offer without sdp", Message{Type: MessageTypeOffer}, errNoSDP},
+		{"answer without sdp", Message{Type: MessageTypeAnswer}, errNoSDP},
+		{"candidate", NewCandidateMessage(EndOfCandidates), nil},
+		{"candidate without candidate", Message{Type: MessageTypeCandidate}, errNoCandidate},
+		{"bye", Message{Type: MessageTypeBye}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.msg.Validate(); !errors.Is(err, test.want) {
+				t.Fatalf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package signaling
+
+import (
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+)
+
+var (
+	errNoSDP            = errors.New("offer or answer without sdp")
+	errNoCandidate      = errors.New("candidate message without candidate")
+	errUnexpectedAnswer = errors.New("answer without a pending offer")
+)
+
+// Negotiator runs perfect negotiation of a PeerConnection over a Signaler, so
+// either peer may (re)negotiate at any time, e.g. after AddTrack, and offers
+// sent at the same time do not deadlock.
+//
+// Both peers send an offer whenever OnNegotiationNeeded fires. When offers
+// collide the impolite peer ignores the remote offer, the polite peer drops its
+// own offer and answers. Exactly one of both peers must be polite.
+//
+// The impolite peer sets an offer as local description when it makes it, so
+// its candidates are gathered right away and trickled once the offer went out.
+// Pion v3 rejects every rollback in checkNextSignalingState, so the polite peer
+// cannot take an applied offer back: it sets its offer as local description
+// once the answer arrives, and dropping it is its rollback.
+//
+// Descriptions are sent in the order they were made, without holding the lock
+// of the Negotiator.
+type Negotiator struct {
+	pc       *webrtc.PeerConnection
+	signaler Signaler
+	polite   bool
+	trickle  *Trickle
+
+	mu sync.Mutex
+	// pendingOffer waits for its answer, applied tells whether it is the local description already
+	pendingOffer *webrtc.SessionDescription
+	applied      bool
+	ignoreOffer  bool
+	queued       bool
+	iceRestart   bool
+	// outbox holds the offers and answers not sent yet, flushing is set while one goroutine sends them
+	outbox   []webrtc.SessionDescription
+	flushing bool
+
+	onError       func(error)
+	onRemoteState func(webrtc.PeerConnectionState)
+}
+
+// NewNegotiator takes over OnNegotiationNeeded and OnICECandidate of pc and
+// negotiates with the remote peer over signaler
+func NewNegotiator(pc *webrtc.PeerConnection, signaler Signaler, polite bool) *Negotiator {
+	n := &Negotiator{
+		pc:       pc,
+		signaler: signaler,
+		polite:   polite,
+		onError: func(err error) {
+			log.Println("cannot negotiate:", err)
+		},
+	}
+	n.trickle = NewTrickle(pc, signaler.SendCandidate)
+
+	// Pion calls the handler from its operations queue, which the offer would wait for
+	pc.OnNegotiationNeeded(func() {
+		go n.negotiate()
+	})
+
+	return n
+}
+
+// OnError sets the handler for errors of negotiations started by
+// OnNegotiationNeeded, by default they are logged
+func (n *Negotiator) OnError(f func(error)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.onError = f
+}
+
+// OnRemoteState sets the handler for the connection states the remote peer
+// sends with Signaler.SendState
+func (n *Negotiator) OnRemoteState(f func(webrtc.PeerConnectionState)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.onRemoteState = f
+}
+
+func (n *Negotiator) negotiate() {
+	if err := n.Negotiate(); err != nil {
+		n.mu.Lock()
+		onError := n.onError
+		n.mu.Unlock()
+
+		onError(err)
+	}
+}
+
+// Negotiate sends an offer now, e.g. to renegotiate without a local change.
+// While another negotiation is in flight the offer follows once it completed.
+func (n *Negotiator) Negotiate() error {
+	n.mu.Lock()
+	err := n.offer()
+	n.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return n.flush()
+}
+
+// RestartICE renegotiates with fresh ICE credentials, e.g. as restart of an ICERestarter
+func (n *Negotiator) RestartICE() error {
+	n.mu.Lock()
+	n.iceRestart = true
+	err := n.offer()
+	n.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return n.flush()
+}
+
+// offer makes an offer and queues it for flush unless a negotiation is in flight, n.mu must be held
+func (n *Negotiator) offer() error {
+	if n.pendingOffer != nil || n.pc.SignalingState() != webrtc.SignalingStateStable {
+		n.queued = true
+		return nil
+	}
+	n.queued = false
+
+	if n.iceRestart {
+		// The restart gathers right away, before the remote peer knows the new credentials
+		n.trickle.Hold()
+	}
+	offer, err := n.pc.CreateOffer(&webrtc.OfferOptions{ICERestart: n.iceRestart})
+	if err != nil {
+		return err
+	}
+	n.iceRestart = false
+
+	// Sets the LocalDescription, and starts our UDP listeners.
+	// The candidates it gathers follow the offer, see flush.
+	n.applied = !n.polite
+	if n.applied {
+		if err := n.trickle.SetLocalDescription(offer); err != nil {
+			return err
+		}
+	}
+	n.pendingOffer = &offer
+	n.outbox = append(n.outbox, offer)
+
+	return nil
+}
+
+// flush sends the outbox in order, unless another goroutine is already sending it
+func (n *Negotiator) flush() error {
+	n.mu.Lock()
+	if n.flushing {
+		n.mu.Unlock()
+		return nil
+	}
+	n.flushing = true
+
+	for len(n.outbox) > 0 {
+		desc := n.outbox[0]
+		n.outbox = n.outbox[1:]
+		n.mu.Unlock()
+
+		var err error
+		if desc.Type == webrtc.SDPTypeOffer {
+			err = n.signaler.SendOffer(desc)
+		} else {
+			err = n.signaler.SendAnswer(desc)
+		}
+
+		n.mu.Lock()
+		if err == nil && desc.Type == webrtc.SDPTypeOffer && !outboxHasOffer(n.outbox) {
+			// The remote peer has the offer, the candidates of the latest one may follow
+			err = n.trickle.SendLocal()
+		}
+		if err != nil {
+			n.flushing = false
+			n.mu.Unlock()
+			return err
+		}
+	}
+
+	n.flushing = false
+	n.mu.Unlock()
+	return nil
+}
+
+func outboxHasOffer(outbox []webrtc.SessionDescription) bool {
+	for _, desc := range outbox {
+		if desc.Type == webrtc.SDPTypeOffer {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle applies a message of the remote peer and hands state messages to
+// OnRemoteState, bye is left to the caller
+func (n *Negotiator) Handle(msg Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	switch msg.Type {
+	case MessageTypeOffer, MessageTypeAnswer:
+		if err := n.handleDescription(*msg.SDP); err != nil {
+			return err
+		}
+		return n.flush()
+	case MessageTypeCandidate:
+		err := n.trickle.AddRemoteCandidate(*msg.Candidate)
+
+		// Candidates of an ignored offer do not match any description
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if err != nil && !n.ignoreOffer {
+			return err
+		}
+	case MessageTypeState:
+		n.mu.Lock()
+		onRemoteState := n.onRemoteState
+		n.mu.Unlock()
+
+		if onRemoteState != nil {
+			onRemoteState(msg.PeerConnectionState())
+		}
+	}
+	return nil
+}
+
+// handleDescription applies desc and queues the answer or the next offer for flush
+func (n *Negotiator) handleDescription(desc webrtc.SessionDescription) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if desc.Type == webrtc.SDPTypeAnswer {
+		if n.pendingOffer == nil {
+			return errUnexpectedAnswer
+		}
+		offer := *n.pendingOffer
+		n.pendingOffer = nil
+
+		if !n.applied {
+			// Sets the LocalDescription, and starts our UDP listeners
+			if err := n.trickle.SetLocalDescription(offer); err != nil {
+				return err
+			}
+		}
+
+		// Pending candidates of both sides are applied and sent as soon as the answer is set
+		if err := n.trickle.SetRemoteDescription(desc); err != nil {
+			return err
+		}
+
+		if n.queued {
+			return n.offer()
+		}
+		return nil
+	}
+
+	collision := n.pendingOffer != nil || n.pc.SignalingState() != webrtc.SignalingStateStable
+	n.ignoreOffer = !n.polite && collision
+	if n.ignoreOffer {
+		return nil
+	}
+
+	if collision {
+		// The polite peer drops its own offer, which was never applied.
+		// Pion fires OnNegotiationNeeded again if the answer does not cover its changes.
+		n.dropOffer()
+	}
+
+	if err := n.trickle.SetRemoteDescription(desc); err != nil {
+		return err
+	}
+
+	answer, err := n.pc.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+
+	// Sets the LocalDescription, and starts our UDP listeners.
+	// The remote peer holds back the candidates it gathers until the answer arrived.
+	if err := n.trickle.SetLocalDescription(answer); err != nil {
+		return err
+	}
+	n.outbox = append(n.outbox, answer)
+
+	if n.queued {
+		return n.offer()
+	}
+	return nil
+}
+
+// dropOffer forgets the pending offer of the polite peer and takes it out of the outbox, n.mu must be held
+func (n *Negotiator) dropOffer() {
+	n.pendingOffer = nil
+
+	outbox := n.outbox[:0]
+	for _, desc := range n.outbox {
+		if desc.Type != webrtc.SDPTypeOffer {
+			outbox = append(outbox, desc)
+		}
+	}
+	n.outbox = outbox
+}
+
+// Run handles the messages of the remote peer until it says bye, and returns
+// the first error of Handle or the Signaler
+func (n *Negotiator) Run() error {
+	for msg := range n.signaler.Recv() {
+		if err := n.Handle(msg); err != nil {
+			return err
+		}
+	}
+	return n.signaler.Err()
+}
@@ -0,0 +1,106 @@
+package signaling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// dataChannelReceived is closed once pc receives a data channel labeled label
+func dataChannelReceived(pc *webrtc.PeerConnection, label string) <-chan struct{} {
+	done := make(chan struct{})
+	pc.OnDataChannel(func(d *webrtc.DataChannel) {
+		if d.Label() == label {
+			d.OnOpen(func() { close(done) })
+		}
+	})
+	return done
+}
+
+func runNegotiator(t *testing.T, n *Negotiator) {
+	t.Helper()
+
+	errs := make(chan error, 1)
+	go func() { errs <- n.Run() }()
+	t.Cleanup(func() {
+		if err := n.signaler.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestNegotiatorGlare(t *testing.T) {
+	impolitePC, politePC := newTestPeerConnection(t), newTestPeerConnection(t)
+	impoliteConnected, politeConnected := connected(impolitePC), connected(politePC)
+	impoliteReceived := dataChannelReceived(impolitePC, "polite")
+	politeReceived := dataChannelReceived(politePC, "impolite")
+
+	a, b := NewMemorySignalers()
+	impolite := NewNegotiator(impolitePC, a, false)
+	polite := NewNegotiator(politePC, b, true)
+
+	// Both peers offer before either reads the offer of the other
+	if _, err := impolitePC.CreateDataChannel("impolite", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := politePC.CreateDataChannel("polite", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := impolite.Negotiate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := polite.Negotiate(); err != nil {
+		t.Fatal(err)
+	}
+	if state := impolitePC.SignalingState(); state != webrtc.SignalingStateHaveLocalOffer {
+		t.Fatalf("impolite signaling state %s before the offers were read, want have-local-offer", state)
+	}
+	polite.mu.Lock()
+	politeOffered := polite.pendingOffer != nil
+	polite.mu.Unlock()
+	if !politeOffered {
+		t.Fatal("the polite peer has no pending offer before the offers were read")
+	}
+
+	runNegotiator(t, impolite)
+	runNegotiator(t, polite)
+
+	waitFor(t, impoliteConnected, "the impolite peer to connect")
+	waitFor(t, politeConnected, "the polite peer to connect")
+	waitFor(t, politeReceived, "the data channel of the impolite peer")
+	waitFor(t, impoliteReceived, "the data channel of the polite peer")
+}
+
+func TestNegotiatorTricklesBeforeAnswer(t *testing.T) {
+	pc := newTestPeerConnection(t)
+	a, b := NewMemorySignalers()
+	defer func() { _ = b.Close() }()
+
+	n := NewNegotiator(pc, a, false)
+	runNegotiator(t, n)
+
+	if _, err := pc.CreateDataChannel("data", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The offer is set as local description right away, so its candidates
+	// follow it without waiting for an answer
+	timeout := time.After(connectTimeout)
+	for _, want := range []MessageType{MessageTypeOffer, MessageTypeCandidate} {
+		select {
+		case msg := <-b.Recv():
+			if msg.Type != want {
+				t.Fatalf("got %s, want %s", msg.Type, want)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for the %s", want)
+		}
+	}
+	if state := pc.SignalingState(); state != webrtc.SignalingStateHaveLocalOffer {
+		t.Fatalf("signaling state %s, want have-local-offer", state)
+	}
+}
@@ -0,0 +1,147 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisKeyPrefix namespaces the channels and keys of RedisConn
+const RedisKeyPrefix = "webrtc-demo:session:"
+
+// redisBacklogTTL is how long messages wait for a peer that did not subscribe yet
+const redisBacklogTTL = 5 * time.Minute
+
+// Role is the side a peer plays in a signaling session
+type Role string
+
+const (
+	RoleOffer  Role = "offer"
+	RoleAnswer Role = "answer"
+)
+
+var errUnknownRole = errors.New("role must be offer or answer")
+
+// publishOrQueue publishes a message and queues it in the backlog of the
+// channel when nobody is subscribed yet. Being a script it runs atomically,
+// so a peer that subscribes and then drains its backlog misses nothing.
+var publishOrQueue = redis.NewScript(`
+if redis.call('PUBLISH', ARGV[1], ARGV[2]) == 0 then
+	redis.call('RPUSH', KEYS[1], ARGV[2])
+	redis.call('EXPIRE', KEYS[1], ARGV[3])
+end
+return 0
+`)
+
+// RedisConn exchanges the Messages of one session over Redis pub/sub, so peers
+// only need to reach Redis, not each other. Every role reads its own channel,
+// see RedisChannel, and publishes to the channel of the other role.
+type RedisConn struct {
+	ctx    context.Context
+	client redis.UniversalClient
+	sub    *redis.PubSub
+
+	inbox, outbox string
+	backlog       []string
+}
+
+// RedisChannel returns the channel the peer playing role receives the messages of sessionID on
+func RedisChannel(sessionID string, role Role) string {
+	return RedisKeyPrefix + sessionID + ":" + string(role)
+}
+
+// NewRedisClient connects to the Redis at url, e.g. redis://localhost:6379/0 or rediss:// for TLS
+func NewRedisClient(url string) (*redis.Client, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return redis.NewClient(options), nil
+}
+
+// DialRedis joins sessionID as role. client may be any Redis, e.g. one in-process for tests.
+// Messages sent by the other role before this call are delivered first.
+func DialRedis(ctx context.Context, client redis.UniversalClient, sessionID string, role Role) (*RedisConn, error) {
+	var peer Role
+	switch role {
+	case RoleOffer:
+		peer = RoleAnswer
+	case RoleAnswer:
+		peer = RoleOffer
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownRole, role)
+	}
+
+	c := &RedisConn{
+		ctx:    ctx,
+		client: client,
+		inbox:  RedisChannel(sessionID, role),
+		outbox: RedisChannel(sessionID, peer),
+	}
+
+	// Wait for the subscription before draining, later messages are published live
+	c.sub = client.Subscribe(ctx, c.inbox)
+	if _, err := c.sub.Receive(ctx); err != nil {
+		_ = c.sub.Close()
+		return nil, err
+	}
+
+	backlog, err := client.LRange(ctx, backlogKey(c.inbox), 0, -1).Result()
+	if err == nil {
+		err = client.Del(ctx, backlogKey(c.inbox)).Err()
+	}
+	if err != nil {
+		_ = c.sub.Close()
+		return nil, err
+	}
+	c.backlog = backlog
+
+	return c, nil
+}
+
+func backlogKey(channel string) string {
+	return channel + ":backlog"
+}
+
+// Send publishes a Message to the other peer of the session
+func (c *RedisConn) Send(msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return publishOrQueue.Run(c.ctx, c.client, []string{backlogKey(c.outbox)},
+		c.outbox, payload, int(redisBacklogTTL.Seconds())).Err()
+}
+
+// Recv blocks until the next Message of the other peer arrives
+func (c *RedisConn) Recv() (Message, error) {
+	msg := Message{}
+
+	var payload string
+	if len(c.backlog) > 0 {
+		payload, c.backlog = c.backlog[0], c.backlog[1:]
+	} else {
+		received, err := c.sub.ReceiveMessage(c.ctx)
+		if err != nil {
+			return msg, err
+		}
+		payload = received.Payload
+	}
+
+	err := json.Unmarshal([]byte(payload), &msg)
+	return msg, err
+}
+
+// Close says bye to the other peer and leaves the session.
+// The bye is not queued, a peer that never joined has nothing to tear down.
+func (c *RedisConn) Close() error {
+	if payload, err := json.Marshal(Message{Type: MessageTypeBye}); err == nil {
+		_ = c.client.Publish(c.ctx, c.outbox, payload).Err()
+	}
+	return c.sub.Close()
+}
@@ -0,0 +1,133 @@
+package signaling
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/pion/webrtc/v3"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return server, client
+}
+
+func dialTestRedis(t *testing.T, client *redis.Client, role Role) *RedisConn {
+	t.Helper()
+
+	conn, err := DialRedis(context.Background(), client, "session", role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.sub.Close()
+	})
+	return conn
+}
+
+func candidateMessage(candidate string) Message {
+	return NewCandidateMessage(webrtc.ICECandidateInit{Candidate: candidate})
+}
+
+func recvCandidate(t *testing.T, conn MessageConn, want string) {
+	t.Helper()
+
+	msg, err := conn.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != MessageTypeCandidate || msg.Candidate == nil || msg.Candidate.Candidate != want {
+		t.Fatalf("got %+v, want candidate %q", msg, want)
+	}
+}
+
+func TestRedisPublishOrQueue(t *testing.T) {
+	server, client := newTestRedis(t)
+	ctx := context.Background()
+	key := backlogKey("channel")
+
+	// Nobody listens, the message waits in the backlog
+	if err := publishOrQueue.Run(ctx, client, []string{key}, "channel", "first", 60).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := server.List(key); err != nil || len(got) != 1 || got[0] != "first" {
+		t.Fatalf("backlog %v, %v, want [first]", got, err)
+	}
+	if ttl := server.TTL(key); ttl <= 0 {
+		t.Fatalf("backlog TTL %s, want one", ttl)
+	}
+
+	// A subscriber gets the message live, the backlog stays as it was
+	sub := client.Subscribe(ctx, "channel")
+	defer func() { _ = sub.Close() }()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := publishOrQueue.Run(ctx, client, []string{key}, "channel", "second", 60).Err(); err != nil {
+		t.Fatal(err)
+	}
+	received, err := sub.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.Payload != "second" {
+		t.Fatalf("got %q, want second", received.Payload)
+	}
+	if got, err := server.List(key); err != nil || len(got) != 1 {
+		t.Fatalf("backlog %v, %v, want only the first message", got, err)
+	}
+}
+
+func TestRedisBacklogOrder(t *testing.T) {
+	server, client := newTestRedis(t)
+	offer := dialTestRedis(t, client, RoleOffer)
+
+	// The answer side joins late, what the offer side sent meanwhile comes first and in order
+	for _, c := range []string{"one", "two"} {
+		if err := offer.Send(candidateMessage(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	answer := dialTestRedis(t, client, RoleAnswer)
+	if server.Exists(backlogKey(RedisChannel("session", RoleAnswer))) {
+		t.Fatal("the backlog was not drained")
+	}
+	if err := offer.Send(candidateMessage("three")); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []string{"one", "two", "three"} {
+		recvCandidate(t, answer, c)
+	}
+
+	// Both are subscribed, messages go live the other way as well
+	if err := answer.Send(candidateMessage("four")); err != nil {
+		t.Fatal(err)
+	}
+	recvCandidate(t, offer, "four")
+
+	if err := answer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := offer.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != MessageTypeBye {
+		t.Fatalf("got %s after Close, want bye", msg.Type)
+	}
+}
+
+func TestDialRedisUnknownRole(t *testing.T) {
+	_, client := newTestRedis(t)
+	if _, err := DialRedis(context.Background(), client, "session", Role("viewer")); err == nil {
+		t.Fatal("DialRedis accepted an unknown role")
+	}
+}
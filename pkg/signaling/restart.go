@@ -0,0 +1,186 @@
+package signaling
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Defaults of ICERestartOptions
+const (
+	DefaultICERestartGracePeriod = 5 * time.Second
+	DefaultICERestartRetries     = 5
+	DefaultICERestartBackoff     = 2 * time.Second
+	DefaultICERestartMaxBackoff  = 30 * time.Second
+)
+
+// ICERestartOptions bounds the recovery of an ICERestarter, zero fields take the defaults
+type ICERestartOptions struct {
+	// GracePeriod is how long a disconnected PeerConnection may come back on its own
+	GracePeriod time.Duration
+	// Retries is the number of ICE restarts before giving up
+	Retries int
+	// Backoff is the wait for a restart to connect before the next one,
+	// it doubles after every restart up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (o ICERestartOptions) withDefaults() ICERestartOptions {
+	if o.GracePeriod <= 0 {
+		o.GracePeriod = DefaultICERestartGracePeriod
+	}
+	if o.Retries <= 0 {
+		o.Retries = DefaultICERestartRetries
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultICERestartBackoff
+	}
+	if o.MaxBackoff < o.Backoff {
+		o.MaxBackoff = max(DefaultICERestartMaxBackoff, o.Backoff)
+	}
+	return o
+}
+
+// ICERestarter recovers a PeerConnection from network changes with ICE restarts,
+// so media and data channels survive short outages instead of failing.
+//
+// Once the PeerConnection is disconnected for GracePeriod, or failed, restart
+// is called to renegotiate with fresh ICE credentials over the signaling channel,
+// e.g. Trickle.RestartICE or Negotiator.RestartICE. Restarts are retried with
+// exponential backoff until the PeerConnection is connected again or Retries
+// are used up, then OnGiveUp is called.
+//
+// A nil restart waits for the remote peer to restart ICE, e.g. on the answering
+// side, and gives up after the same time.
+type ICERestarter struct {
+	restart func() error
+	opts    ICERestartOptions
+
+	mu            sync.Mutex
+	cancel        context.CancelFunc
+	onStateChange func(webrtc.PeerConnectionState)
+	onGiveUp      func()
+}
+
+// NewICERestarter takes over OnConnectionStateChange of pc, see OnConnectionStateChange
+func NewICERestarter(pc *webrtc.PeerConnection, restart func() error, opts ICERestartOptions) *ICERestarter {
+	r := &ICERestarter{
+		restart: restart,
+		opts:    opts.withDefaults(),
+		onGiveUp: func() {
+			log.Println("ICE restarts did not reconnect, giving up")
+		},
+	}
+	pc.OnConnectionStateChange(r.handleStateChange)
+	return r
+}
+
+// OnConnectionStateChange sets the handler for connection state changes of the PeerConnection
+func (r *ICERestarter) OnConnectionStateChange(f func(webrtc.PeerConnectionState)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onStateChange = f
+}
+
+// OnGiveUp sets the handler called once the restarts did not reconnect, by default it logs
+func (r *ICERestarter) OnGiveUp(f func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onGiveUp = f
+}
+
+func (r *ICERestarter) handleStateChange(state webrtc.PeerConnectionState) {
+	r.mu.Lock()
+	onStateChange := r.onStateChange
+	r.mu.Unlock()
+
+	if onStateChange != nil {
+		onStateChange(state)
+	}
+
+	switch state { // nolint:exhaustive
+	case webrtc.PeerConnectionStateDisconnected:
+		r.recover(r.opts.GracePeriod)
+	case webrtc.PeerConnectionStateFailed:
+		r.recover(0)
+	case webrtc.PeerConnectionStateConnected, webrtc.PeerConnectionStateClosed:
+		r.stop()
+	}
+}
+
+// recover starts restarting after delay unless a recovery is running already
+func (r *ICERestarter) recover(delay time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go r.run(ctx, delay)
+}
+
+func (r *ICERestarter) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
+
+func (r *ICERestarter) run(ctx context.Context, delay time.Duration) {
+	backoff := r.opts.Backoff
+
+	for attempt := 1; attempt <= r.opts.Retries; attempt++ {
+		if !sleep(ctx, delay) {
+			return
+		}
+
+		if r.restart != nil {
+			log.Printf("ICE restart %d/%d\n", attempt, r.opts.Retries)
+			if err := r.restart(); err != nil {
+				log.Println("ICE restart failed:", err)
+			}
+		}
+
+		delay, backoff = backoff, min(2*backoff, r.opts.MaxBackoff)
+	}
+	if !sleep(ctx, delay) {
+		return
+	}
+
+	r.mu.Lock()
+	if ctx.Err() != nil {
+		r.mu.Unlock()
+		return
+	}
+	onGiveUp := r.onGiveUp
+	r.cancel()
+	r.cancel = nil
+	r.mu.Unlock()
+
+	onGiveUp()
+}
+
+// sleep waits for d and reports false when ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
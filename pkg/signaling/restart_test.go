@@ -0,0 +1,153 @@
+package signaling
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+var testRestartOptions = ICERestartOptions{
+	GracePeriod: 50 * time.Millisecond,
+	Retries:     3,
+	Backoff:     20 * time.Millisecond,
+	MaxBackoff:  40 * time.Millisecond,
+}
+
+// newTestRestarter counts the restarts of an ICERestarter and closes the returned channel when it gives up
+func newTestRestarter(t *testing.T, restartErr error) (*ICERestarter, *atomic.Int32, chan struct{}) {
+	t.Helper()
+
+	restarts := &atomic.Int32{}
+	r := NewICERestarter(newTestPeerConnection(t), func() error {
+		restarts.Add(1)
+		return restartErr
+	}, testRestartOptions)
+
+	gaveUp := make(chan struct{})
+	r.OnGiveUp(func() { close(gaveUp) })
+	return r, restarts, gaveUp
+}
+
+func TestICERestarterGivesUp(t *testing.T) {
+	r, restarts, gaveUp := newTestRestarter(t, errors.New("signaling is down"))
+	r.handleStateChange(webrtc.PeerConnectionStateFailed)
+	// Further failures while recovering do not start another recovery
+	r.handleStateChange(webrtc.PeerConnectionStateFailed)
+
+	select {
+	case <-gaveUp:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the restarter did not give up")
+	}
+	if got := restarts.Load(); got != int32(testRestartOptions.Retries) {
+		t.Fatalf("%d restarts, want %d", got, testRestartOptions.Retries)
+	}
+}
+
+func TestICERestarterRecovers(t *testing.T) {
+	r, restarts, gaveUp := newTestRestarter(t, nil)
+
+	// Back within the grace period, nothing is restarted
+	r.handleStateChange(webrtc.PeerConnectionStateDisconnected)
+	r.handleStateChange(webrtc.PeerConnectionStateConnected)
+	time.Sleep(2 * testRestartOptions.GracePeriod)
+	if got := restarts.Load(); got != 0 {
+		t.Fatalf("%d restarts after a short disconnect, want none", got)
+	}
+
+	// Connected after the first restart, the recovery stops
+	r.handleStateChange(webrtc.PeerConnectionStateFailed)
+	deadline := time.Now().Add(5 * time.Second)
+	for restarts.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	r.handleStateChange(webrtc.PeerConnectionStateConnected)
+
+	select {
+	case <-gaveUp:
+		t.Fatal("the restarter gave up after it reconnected")
+	case <-time.After(4 * testRestartOptions.MaxBackoff * time.Duration(testRestartOptions.Retries)):
+	}
+	if got := restarts.Load(); got != 1 {
+		t.Fatalf("%d restarts, want 1", got)
+	}
+}
+
+func TestICERestartOptionsDefaults(t *testing.T) {
+	got := ICERestartOptions{Backoff: time.Minute}.withDefaults()
+	want := ICERestartOptions{
+		GracePeriod: DefaultICERestartGracePeriod,
+		Retries:     DefaultICERestartRetries,
+		Backoff:     time.Minute,
+		MaxBackoff:  time.Minute,
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+// iceUfrag returns the ice-ufrag of desc
+func iceUfrag(desc *webrtc.SessionDescription) string {
+	if desc == nil {
+		return ""
+	}
+	_, ufrag, _ := strings.Cut(desc.SDP, "a=ice-ufrag:")
+	ufrag, _, _ = strings.Cut(ufrag, "\r\n")
+	return ufrag
+}
+
+func TestNegotiatorRestartICE(t *testing.T) {
+	offerPC, answerPC := newTestPeerConnection(t), newTestPeerConnection(t)
+	offerConnected := connected(offerPC)
+
+	messages := make(chan string, 1)
+	answerPC.OnDataChannel(func(d *webrtc.DataChannel) {
+		d.OnMessage(func(msg webrtc.DataChannelMessage) {
+			messages <- string(msg.Data)
+		})
+	})
+
+	a, b := NewMemorySignalers()
+	offer := NewNegotiator(offerPC, a, false)
+	runNegotiator(t, offer)
+	runNegotiator(t, NewNegotiator(answerPC, b, true))
+
+	dataChannel, err := offerPC.CreateDataChannel("data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened := make(chan struct{})
+	dataChannel.OnOpen(func() { close(opened) })
+	waitFor(t, offerConnected, "the offerer to connect")
+	waitFor(t, opened, "the data channel")
+
+	before := iceUfrag(answerPC.RemoteDescription())
+	if err := offer.RestartICE(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The answerer applied the new credentials and the data channel carries on
+	deadline := time.Now().Add(connectTimeout)
+	for iceUfrag(answerPC.RemoteDescription()) == before || answerPC.SignalingState() != webrtc.SignalingStateStable ||
+		offerPC.SignalingState() != webrtc.SignalingStateStable || offerPC.ICEConnectionState() != webrtc.ICEConnectionStateConnected {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the ICE restart")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := dataChannel.SendText("after the restart"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-messages:
+		if msg != "after the restart" {
+			t.Fatalf("got %q", msg)
+		}
+	case <-time.After(connectTimeout):
+		t.Fatal("no message after the ICE restart")
+	}
+}
@@ -0,0 +1,502 @@
+package signaling
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pion/webrtc/v3"
+)
+
+// SessionServer answers many concurrent offerers, each negotiation is scoped by a session ID:
+//
+//	POST   /sessions/:id/sdp         offer in, answer out (JSON SessionDescription)
+//	POST   /sessions/:id/candidate   trickled candidate of the offerer (JSON ICECandidateInit)
+//	GET    /sessions/:id/candidates  candidates of the server as Server-Sent Events
+//	DELETE /sessions/:id             close the session
+//
+// The offerer picks the ID. Every session gets its own PeerConnection from
+// newPeerConnection and is dropped once it fails or is closed.
+//
+// The server waits for ICE gathering before answering, so only the offerer has
+// to trickle. With POST /sessions/:id/sdp?trickle=true it answers right away and
+// the offerer reads the candidates of the server from the candidates stream,
+// so an offerer without an inbound HTTP port, e.g. behind NAT, trickles both ways.
+//
+// Request bodies, requests per session, candidates per session and the number of
+// sessions are bounded by DefaultLimits, see SetLimits, and sessions that do not
+// connect within its ConnectTimeout are closed. Per client IP limits are up to
+// Limits.Middleware.
+type SessionServer struct {
+	newPeerConnection func(id string) (*webrtc.PeerConnection, error)
+
+	limits          Limits
+	sessionRequests *RateLimiter
+
+	mu       sync.Mutex
+	sessions map[string]*session
+
+	router *httprouter.Router
+}
+
+// session is the PeerConnection of one offerer and the local candidates it gathered so far
+type session struct {
+	pc      *webrtc.PeerConnection
+	trickle *Trickle
+	// negotiate serializes the offers of the session, one is answered at a time
+	negotiate sync.Mutex
+
+	mu sync.Mutex
+	// candidates are those of the latest ICE credentials, an ICE restart
+	// starts a new generation of them
+	candidates []webrtc.ICECandidateInit
+	generation int
+	// remoteCandidates counts the candidates the offerer trickled
+	remoteCandidates int
+	// changed is closed and replaced whenever a candidate is added
+	changed chan struct{}
+	closed  chan struct{}
+}
+
+func (s *session) addCandidate(c webrtc.ICECandidateInit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.candidates = append(s.candidates, c)
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return nil
+}
+
+// restartCandidates drops the candidates of the previous ICE credentials
+func (s *session) restartCandidates() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.candidates = nil
+	s.generation++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// countRemoteCandidate counts a trickled candidate and reports whether it is
+// within maxCandidates, a limit that is not positive allows any number
+func (s *session) countRemoteCandidate(maxCandidates int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if maxCandidates > 0 && s.remoteCandidates >= maxCandidates {
+		return false
+	}
+	s.remoteCandidates++
+	return true
+}
+
+// candidatesSince returns the candidates after the first n of generation, every
+// candidate when there is a later generation, the current generation and a
+// channel that is closed once there are more
+func (s *session) candidatesSince(generation, n int) ([]webrtc.ICECandidateInit, int, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if generation != s.generation {
+		n = 0
+	}
+	return s.candidates[n:], s.generation, s.changed
+}
+
+// NewSessionServer creates a SessionServer, newPeerConnection creates the
+// PeerConnection of a new session and registers its OnTrack or OnDataChannel.
+// The server owns OnConnectionStateChange and OnICECandidate.
+func NewSessionServer(newPeerConnection func(id string) (*webrtc.PeerConnection, error)) *SessionServer {
+	s := &SessionServer{
+		newPeerConnection: newPeerConnection,
+		sessions:          map[string]*session{},
+		router:            httprouter.New(),
+	}
+	s.SetLimits(DefaultLimits)
+
+	s.router.POST("/sessions/:id/sdp", s.limit(s.handleSDP))
+	s.router.POST("/sessions/:id/candidate", s.limit(s.handleCandidate))
+	s.router.GET("/sessions/:id/candidates", s.limit(s.handleCandidates))
+	s.router.DELETE("/sessions/:id", s.limit(s.handleDelete))
+
+	return s
+}
+
+// SetLimits replaces DefaultLimits, call it before serving.
+// RequestsPerSecond and Burst are ignored, they apply per client IP in Limits.Middleware.
+func (s *SessionServer) SetLimits(limits Limits) {
+	s.limits = limits
+	s.sessionRequests = NewRateLimiter(limits.SessionRequestsPerSecond, limits.SessionBurst)
+}
+
+// limit rate limits the requests of each session and caps their bodies before handle
+func (s *SessionServer) limit(handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if !s.sessionRequests.Allow(p.ByName("id")) {
+			TooManyRequests(w, "session rate limit exceeded")
+			return
+		}
+		LimitBody(w, r, s.limits.MaxBodyBytes)
+		handle(w, r, p)
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (s *SessionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// Len returns the number of live sessions
+func (s *SessionServer) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.sessions)
+}
+
+func (s *SessionServer) handleSDP(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	offer := webrtc.SessionDescription{}
+	if err := json.NewDecoder(r.Body).Decode(&offer); err != nil {
+		http.Error(w, err.Error(), BodyErrorStatus(err))
+		return
+	}
+	if offer.Type != webrtc.SDPTypeOffer {
+		http.Error(w, "expected an offer", http.StatusBadRequest)
+		return
+	}
+
+	id := p.ByName("id")
+	sess, created, err := s.getOrCreate(id)
+	if errors.Is(err, ErrTooManySessions) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	trickle, _ := strconv.ParseBool(r.URL.Query().Get("trickle"))
+	sess.negotiate.Lock()
+	answer, err := answerOffer(sess, offer, !trickle)
+	sess.negotiate.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		// A broken first offer must not leave an orphaned session behind
+		if created && s.remove(id, sess) {
+			s.close(id, sess)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(answer); err != nil {
+		log.Printf("session %s: cannot write answer: %v\n", id, err)
+	}
+}
+
+func (s *SessionServer) handleCandidate(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	sess := s.get(p.ByName("id"))
+	if sess == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	c := webrtc.ICECandidateInit{}
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, err.Error(), BodyErrorStatus(err))
+		return
+	}
+	if !sess.countRemoteCandidate(s.limits.MaxCandidatesPerSession) {
+		// Retrying does not help, there is no Retry-After
+		http.Error(w, "too many candidates for this session", http.StatusTooManyRequests)
+		return
+	}
+	if err := sess.trickle.AddRemoteCandidate(c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+// handleCandidates streams every local candidate of the session as a "candidate"
+// event, starting with those gathered before the request. Only candidates of the
+// latest ICE credentials are sent, a stream that is open during an ICE restart
+// goes on with the new ones. The stream ends after EndOfCandidates or when the
+// session is closed.
+func (s *SessionServer) handleCandidates(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	sess := s.get(p.ByName("id"))
+	if sess == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	generation, sent := 0, 0
+	for {
+		candidates, current, changed := sess.candidatesSince(generation, sent)
+		if current != generation {
+			generation, sent = current, 0
+		}
+		for _, c := range candidates {
+			payload, err := json.Marshal(c)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", MessageTypeCandidate, payload); err != nil {
+				return
+			}
+			sent++
+
+			if IsEndOfCandidates(c) {
+				flusher.Flush()
+				return
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-sess.closed:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *SessionServer) handleDelete(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	sess := s.get(id)
+	if sess == nil || !s.remove(id, sess) {
+		http.NotFound(w, r)
+		return
+	}
+	if err := sess.pc.Close(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *SessionServer) getOrCreate(id string) (*session, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, ok := s.sessions[id]; ok {
+		return sess, false, nil
+	}
+	if s.limits.MaxSessions > 0 && len(s.sessions) >= s.limits.MaxSessions {
+		return nil, false, ErrTooManySessions
+	}
+
+	peerConnection, err := s.newPeerConnection(id)
+	if err != nil {
+		return nil, false, err
+	}
+	sess := &session{
+		pc:      peerConnection,
+		changed: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+	sess.trickle = NewTrickle(peerConnection, sess.addCandidate)
+
+	// A later session may reuse the ID, only this one is dropped
+	connected := atomic.Bool{}
+	peerConnection.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		log.Printf("session %s: %s\n", id, state)
+
+		switch state {
+		case webrtc.PeerConnectionStateConnected:
+			connected.Store(true)
+		case webrtc.PeerConnectionStateFailed:
+			if s.remove(id, sess) {
+				go s.close(id, sess)
+			}
+		case webrtc.PeerConnectionStateClosed:
+			s.remove(id, sess)
+		}
+	})
+	s.sessions[id] = sess
+
+	if s.limits.ConnectTimeout > 0 {
+		time.AfterFunc(s.limits.ConnectTimeout, func() {
+			if !connected.Load() && s.remove(id, sess) {
+				log.Printf("session %s: not connected after %s\n", id, s.limits.ConnectTimeout)
+				s.close(id, sess)
+			}
+		})
+	}
+
+	return sess, true, nil
+}
+
+func (s *SessionServer) get(id string) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sessions[id]
+}
+
+// close closes the PeerConnection of a removed session
+func (s *SessionServer) close(id string, sess *session) {
+	if err := sess.pc.Close(); err != nil {
+		log.Printf("session %s: cannot close peerConnection: %v\n", id, err)
+	}
+}
+
+// remove drops session id if it still is sess and reports whether it did
+func (s *SessionServer) remove(id string, sess *session) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessions[id] != sess {
+		return false
+	}
+	close(sess.closed)
+	delete(s.sessions, id)
+	return true
+}
+
+// answerOffer applies the offer and returns the answer, holding every local
+// candidate when waitForCandidates is set
+func answerOffer(sess *session, offer webrtc.SessionDescription, waitForCandidates bool) (*webrtc.SessionDescription, error) {
+	if err := sess.trickle.SetRemoteDescription(offer); err != nil {
+		return nil, err
+	}
+
+	answer, err := sess.pc.CreateAnswer(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// An ICE restart gathers anew, the candidates of the old credentials are stale
+	if current := sess.pc.LocalDescription(); current != nil {
+		_, oldUfrag := bundleICE(*current)
+		_, newUfrag := bundleICE(answer)
+		if oldUfrag != nil && newUfrag != nil && *oldUfrag != *newUfrag {
+			sess.restartCandidates()
+		}
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(sess.pc)
+	if err := sess.trickle.SetLocalDescription(answer); err != nil {
+		return nil, err
+	}
+	if waitForCandidates {
+		<-gatherComplete
+	}
+
+	return sess.pc.LocalDescription(), nil
+}
+
+// SessionURL returns the URL of a session resource on a SessionServer at baseURL,
+// e.g. SessionURL("https://localhost:60000", "abc", "sdp")
+func SessionURL(baseURL, id, resource string) string {
+	return fmt.Sprintf("%s/sessions/%s/%s", baseURL, id, resource)
+}
+
+// PostOffer sends an offer to a SessionServer and returns its answer.
+// Append ?trickle=true to url to get the answer before the server gathered
+// its candidates, and read them with StreamCandidates. client sends the request.
+func PostOffer(client *http.Client, url string, offer webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	answer := webrtc.SessionDescription{}
+
+	payload, err := json.Marshal(offer)
+	if err != nil {
+		return answer, err
+	}
+
+	resp, err := client.Post(url, "application/json; charset=utf-8", bytes.NewReader(payload)) // nolint:noctx
+	if err != nil {
+		return answer, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return answer, fmt.Errorf("offer rejected: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&answer)
+	return answer, err
+}
+
+// StreamCandidates reads the candidates stream of a SessionServer at url, e.g.
+// SessionURL(baseURL, id, "candidates"), and hands every candidate to onCandidate,
+// EndOfCandidates included. It returns nil after EndOfCandidates, or the first
+// error of the stream, ctx or onCandidate. client sends the request.
+func StreamCandidates(ctx context.Context, client *http.Client, url string, onCandidate func(webrtc.ICECandidateInit) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("candidate stream rejected: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	event, data := "", []string{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch {
+		case line == "":
+			// A blank line dispatches the event
+			if event == string(MessageTypeCandidate) && len(data) > 0 {
+				c := webrtc.ICECandidateInit{}
+				if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &c); err != nil {
+					return err
+				}
+				if err := onCandidate(c); err != nil {
+					return err
+				}
+				if IsEndOfCandidates(c) {
+					return nil
+				}
+			}
+			event, data = "", data[:0]
+		case field == "event":
+			event = value
+		case field == "data":
+			data = append(data, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
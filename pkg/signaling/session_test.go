@@ -0,0 +1,271 @@
+package signaling
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func newTestSessionServer(t *testing.T) (*SessionServer, *httptest.Server) {
+	t.Helper()
+
+	sessions := NewSessionServer(func(id string) (*webrtc.PeerConnection, error) {
+		pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+		if err == nil {
+			t.Cleanup(func() {
+				_ = pc.Close()
+			})
+		}
+		return pc, err
+	})
+	server := httptest.NewServer(sessions)
+	t.Cleanup(server.Close)
+	return sessions, server
+}
+
+// newTestOffer is an offer with a data channel, of a PeerConnection closed with the test
+func newTestOffer(t *testing.T) webrtc.SessionDescription {
+	t.Helper()
+
+	pc := newTestPeerConnection(t)
+	if _, err := pc.CreateDataChannel("data", nil); err != nil {
+		t.Fatal(err)
+	}
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return offer
+}
+
+func TestSessionServerRemovesOnlyItsSession(t *testing.T) {
+	sessions, server := newTestSessionServer(t)
+	url := SessionURL(server.URL, "reused", "sdp") + "?trickle=true"
+
+	if _, err := PostOffer(http.DefaultClient, url, newTestOffer(t)); err != nil {
+		t.Fatal(err)
+	}
+	first := sessions.get("reused")
+	if !sessions.remove("reused", first) {
+		t.Fatal("the first session was not removed")
+	}
+
+	// A new session takes the ID before the first one reports closed
+	if _, err := PostOffer(http.DefaultClient, url, newTestOffer(t)); err != nil {
+		t.Fatal(err)
+	}
+	second := sessions.get("reused")
+	if second == nil || second == first {
+		t.Fatal("no new session for the ID")
+	}
+	if sessions.remove("reused", first) {
+		t.Fatal("the first session removed the second one")
+	}
+	if got := sessions.get("reused"); got != second {
+		t.Fatal("the second session is gone")
+	}
+}
+
+func TestSessionServerSerializesOffers(t *testing.T) {
+	sessions, server := newTestSessionServer(t)
+	url := SessionURL(server.URL, "busy", "sdp") + "?trickle=true"
+	offer := newTestOffer(t)
+
+	// The same offer again is a renegotiation without changes, it only fails
+	// when two answers are made at once
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := PostOffer(http.DefaultClient, url, offer); err != nil {
+				errs <- fmt.Errorf("offer %d: %w", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := sessions.Len(); n != 1 {
+		t.Fatalf("%d sessions, want 1", n)
+	}
+}
+
+func TestSessionServerLimits(t *testing.T) {
+	sessions, server := newTestSessionServer(t)
+	sessions.SetLimits(Limits{MaxSessions: 1, ConnectTimeout: 200 * time.Millisecond})
+
+	if _, err := PostOffer(http.DefaultClient, SessionURL(server.URL, "first", "sdp")+"?trickle=true", newTestOffer(t)); err != nil {
+		t.Fatal(err)
+	}
+	_, err := PostOffer(http.DefaultClient, SessionURL(server.URL, "second", "sdp")+"?trickle=true", newTestOffer(t))
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("offer over MaxSessions: got %v, want 503", err)
+	}
+
+	// Nobody answers the checks of the server, so the session never connects
+	deadline := time.Now().Add(5 * time.Second)
+	for sessions.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the session that did not connect was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := PostOffer(http.DefaultClient, SessionURL(server.URL, "second", "sdp")+"?trickle=true", newTestOffer(t)); err != nil {
+		t.Fatalf("offer after the timeout: %v", err)
+	}
+}
+
+// streamCandidates collects what StreamCandidates reads from url
+func streamCandidates(t *testing.T, url string) ([]webrtc.ICECandidateInit, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	candidates := []webrtc.ICECandidateInit{}
+	err := StreamCandidates(ctx, http.DefaultClient, url, func(c webrtc.ICECandidateInit) error {
+		candidates = append(candidates, c)
+		return nil
+	})
+	return candidates, err
+}
+
+func TestStreamCandidatesEvents(t *testing.T) {
+	stream := ": comments and other events are skipped\n\n" +
+		"event: state\ndata: \"connected\"\n\n" +
+		"event: candidate\ndata: {\"candidate\":\"candidate:1 1 udp 2130706431 127.0.0.1 5000 typ host\",\n" +
+		"data: \"sdpMid\":\"0\"}\n\n" +
+		"event: candidate\ndata: {\"candidate\":\"\"}\n\n" +
+		"event: candidate\ndata: {\"candidate\":\"after the end\"}\n\n"
+	ended := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, stream)
+	}))
+	t.Cleanup(ended.Close)
+
+	candidates, err := streamCandidates(t, ended.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 2 || candidates[0].SDPMid == nil || *candidates[0].SDPMid != "0" || !IsEndOfCandidates(candidates[1]) {
+		t.Fatalf("got %+v, want one candidate of mid 0 and the end of candidates", candidates)
+	}
+
+	// A stream that breaks off before the end of candidates is an error
+	cut := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, strings.Split(stream, "event: candidate\ndata: {\"candidate\":\"\"}")[0])
+	}))
+	t.Cleanup(cut.Close)
+	if _, err := streamCandidates(t, cut.URL); err != io.ErrUnexpectedEOF {
+		t.Fatalf("got %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestSessionServerStreamsCandidates(t *testing.T) {
+	_, server := newTestSessionServer(t)
+
+	// A trickled answer comes before the server gathered its candidates
+	answer, err := PostOffer(http.DefaultClient, SessionURL(server.URL, "sse", "sdp")+"?trickle=true", newTestOffer(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Type != webrtc.SDPTypeAnswer {
+		t.Fatalf("got %s, want an answer", answer.Type)
+	}
+
+	candidates, err := streamCandidates(t, SessionURL(server.URL, "sse", "candidates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) < 2 || !IsEndOfCandidates(candidates[len(candidates)-1]) {
+		t.Fatalf("got %+v, want candidates and the end of candidates", candidates)
+	}
+	for _, c := range candidates[:len(candidates)-1] {
+		if c.SDPMid == nil || c.UsernameFragment == nil {
+			t.Fatalf("candidate %+v lacks its mid or ufrag", c)
+		}
+	}
+
+	// Every candidate is sent again to a late reader
+	again, err := streamCandidates(t, SessionURL(server.URL, "sse", "candidates"))
+	if err != nil || len(again) != len(candidates) {
+		t.Fatalf("second stream got %d candidates, %v, want %d", len(again), err, len(candidates))
+	}
+
+	resp, err := http.Get(SessionURL(server.URL, "unknown", "candidates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown session: status %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestSessionServerStreamsLatestCandidates(t *testing.T) {
+	_, server := newTestSessionServer(t)
+	url := SessionURL(server.URL, "restart", "sdp") + "?trickle=true"
+
+	pc := newTestPeerConnection(t)
+	if _, err := pc.CreateDataChannel("data", nil); err != nil {
+		t.Fatal(err)
+	}
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := PostOffer(http.DefaultClient, url, offer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := streamCandidates(t, SessionURL(server.URL, "restart", "candidates")); err != nil {
+		t.Fatal(err)
+	}
+
+	// After an ICE restart only the candidates of the new credentials are streamed
+	offer, err = pc.CreateOffer(&webrtc.OfferOptions{ICERestart: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err = PostOffer(http.DefaultClient, url, offer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ufrag := bundleICE(answer)
+	if ufrag == nil {
+		t.Fatal("answer without ice-ufrag")
+	}
+
+	candidates, err := streamCandidates(t, SessionURL(server.URL, "restart", "candidates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) < 2 || !IsEndOfCandidates(candidates[len(candidates)-1]) {
+		t.Fatalf("got %+v, want candidates and the end of candidates", candidates)
+	}
+	for _, c := range candidates[:len(candidates)-1] {
+		if c.UsernameFragment == nil || *c.UsernameFragment != *ufrag {
+			t.Fatalf("candidate %+v is not of the restarted ufrag %s", c, *ufrag)
+		}
+	}
+}
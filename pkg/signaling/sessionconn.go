@@ -0,0 +1,179 @@
+package signaling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+)
+
+var errSessionAnswer = errors.New("a SessionServer only takes offers and candidates")
+
+// SessionConn is the offerer side of one session of a SessionServer as a
+// MessageConn, wrap it with NewConnSignaler to negotiate over it.
+//
+// Offers are posted and their answers received right away. The first offer
+// asks for a trickled answer and opens the candidates stream of the server, so
+// the offerer needs no inbound HTTP port; later offers, e.g. ICE restarts, are
+// answered with the new candidates of the server. Bye deletes the session.
+type SessionConn struct {
+	client  *http.Client
+	baseURL string
+	id      string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	offered bool
+	// inbox is unbounded, so a flood of candidates cannot block the answer of an offer
+	inbox     []Message
+	notify    chan struct{}
+	closeOnce sync.Once
+}
+
+// DialSession opens session id on the SessionServer at baseURL, e.g.
+// http://localhost:60000. Requests are sent with client.
+func DialSession(client *http.Client, baseURL, id string) *SessionConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SessionConn{
+		client:  client,
+		baseURL: baseURL,
+		id:      id,
+		notify:  make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Send posts offers and candidates to the session, a bye deletes it
+func (c *SessionConn) Send(msg Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	switch msg.Type {
+	case MessageTypeOffer:
+		return c.sendOffer(msg)
+	case MessageTypeCandidate:
+		return PostCandidate(c.client, SessionURL(c.baseURL, c.id, "candidate"), *msg.Candidate)
+	case MessageTypeAnswer:
+		return errSessionAnswer
+	case MessageTypeBye:
+		return c.delete()
+	}
+	// The server keeps track of the connection state itself
+	return nil
+}
+
+func (c *SessionConn) sendOffer(msg Message) error {
+	c.mu.Lock()
+	first := !c.offered
+	c.offered = true
+	c.mu.Unlock()
+
+	url := SessionURL(c.baseURL, c.id, "sdp")
+	if first {
+		url += "?trickle=true"
+	}
+	answer, err := PostOffer(c.client, url, *msg.SDP)
+	if err != nil {
+		return err
+	}
+	if err := c.deliver(NewSDPMessage(answer)); err != nil {
+		return err
+	}
+
+	// The candidates of the server follow the first answer
+	if first {
+		go c.streamCandidates()
+	}
+	return nil
+}
+
+func (c *SessionConn) streamCandidates() {
+	err := StreamCandidates(c.ctx, c.client, SessionURL(c.baseURL, c.id, "candidates"), func(candidate webrtc.ICECandidateInit) error {
+		return c.deliver(NewCandidateMessage(candidate))
+	})
+	if err != nil && c.ctx.Err() == nil {
+		log.Printf("session %s: candidate stream ended: %v\n", c.id, err)
+	}
+}
+
+// deliver queues msg for Recv unless the conn is closed, it never blocks
+func (c *SessionConn) deliver(msg Message) error {
+	if c.ctx.Err() != nil {
+		return io.ErrClosedPipe
+	}
+
+	c.mu.Lock()
+	c.inbox = append(c.inbox, msg)
+	c.mu.Unlock()
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Recv blocks until the next answer or candidate of the server arrives, it returns io.EOF once closed
+func (c *SessionConn) Recv() (Message, error) {
+	for {
+		c.mu.Lock()
+		if len(c.inbox) > 0 {
+			msg := c.inbox[0]
+			c.inbox = c.inbox[1:]
+			c.mu.Unlock()
+			return msg, nil
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-c.notify:
+		case <-c.ctx.Done():
+			return Message{}, io.EOF
+		}
+	}
+}
+
+// Close deletes the session on the server and stops the candidates stream
+func (c *SessionConn) Close() error {
+	err := error(nil)
+	c.closeOnce.Do(func() {
+		err = c.delete()
+		c.cancel()
+	})
+	return err
+}
+
+// delete closes the session on the server, unless no offer ever created it
+func (c *SessionConn) delete() error {
+	c.mu.Lock()
+	offered := c.offered
+	c.mu.Unlock()
+	if !offered {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/sessions/%s", c.baseURL, c.id), nil) // nolint:noctx
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The server already dropped a session that failed or was closed
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("cannot delete session %s: %s", c.id, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package signaling
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func TestSessionConnDeliverNeverBlocks(t *testing.T) {
+	c := DialSession(http.DefaultClient, "http://127.0.0.1:1", "flood")
+
+	// Far more candidates than any buffer, with nobody reading
+	const n = 1000
+	for i := 0; i < n; i++ {
+		if err := c.deliver(NewCandidateMessage(webrtc.ICECandidateInit{Candidate: "candidate"})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.deliver(NewSDPMessage(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: "answer"})); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < n; i++ {
+		if msg, err := c.Recv(); err != nil || msg.Type != MessageTypeCandidate {
+			t.Fatalf("message %d: got %+v, %v, want a candidate", i, msg, err)
+		}
+	}
+	if msg, err := c.Recv(); err != nil || msg.Type != MessageTypeAnswer {
+		t.Fatalf("got %+v, %v, want the answer", msg, err)
+	}
+
+	// No offer was sent, so Close has no session to delete
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Recv(); !errors.Is(err, io.EOF) {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+	if err := c.deliver(NewCandidateMessage(EndOfCandidates)); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("got %v, want io.ErrClosedPipe", err)
+	}
+}
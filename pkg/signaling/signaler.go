@@ -0,0 +1,340 @@
+package signaling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"webrtc-demo/pkg/config"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Transports of OpenSignaler
+const (
+	TransportHTTP      = "http"
+	TransportWebSocket = "websocket"
+	TransportRedis     = "redis"
+	TransportStdio     = "stdio"
+	TransportFile      = "file"
+	TransportGRPC      = "grpc"
+)
+
+var errUnknownTransport = errors.New("unknown signaling transport")
+
+// Signaler carries the negotiation of a single session to the remote peer,
+// whatever the transport. Offer and answer programs only talk to a Signaler
+// so they run unchanged over HTTP, WebSocket, gRPC, Redis, stdio, a shared
+// directory or in memory.
+type Signaler interface {
+	SendOffer(offer webrtc.SessionDescription) error
+	SendAnswer(answer webrtc.SessionDescription) error
+	SendCandidate(c webrtc.ICECandidateInit) error
+	// SendState tells the remote peer the connection state of this one
+	SendState(state webrtc.PeerConnectionState) error
+
+	// Recv streams the messages of the remote peer. It is closed after
+	// a bye, on Close or when the transport fails, see Err.
+	Recv() <-chan Message
+	// Err returns why Recv was closed, nil after a bye or Close
+	Err() error
+
+	// Close says bye to the remote peer and releases the transport
+	Close() error
+}
+
+// MessageConn is a bidirectional stream of Messages, e.g. a WebSocketConn or a RedisConn
+type MessageConn interface {
+	Send(msg Message) error
+	Recv() (Message, error)
+	Close() error
+}
+
+// connSignaler implements Signaler on top of a MessageConn
+type connSignaler struct {
+	conn MessageConn
+	recv chan Message
+	done chan struct{}
+
+	mu        sync.Mutex
+	err       error
+	closeOnce sync.Once
+}
+
+// NewConnSignaler turns a MessageConn into a Signaler, it reads conn until a bye or an error
+func NewConnSignaler(conn MessageConn) Signaler {
+	s := &connSignaler{
+		conn: conn,
+		recv: make(chan Message, 16),
+		done: make(chan struct{}),
+	}
+	go s.readLoop()
+	return s
+}
+
+func (s *connSignaler) readLoop() {
+	defer close(s.recv)
+
+	for {
+		msg, err := s.conn.Recv()
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				if !errors.Is(err, io.EOF) {
+					s.mu.Lock()
+					s.err = err
+					s.mu.Unlock()
+				}
+			}
+			return
+		}
+
+		select {
+		case s.recv <- msg:
+		case <-s.done:
+			return
+		}
+		if msg.Type == MessageTypeBye {
+			return
+		}
+	}
+}
+
+func (s *connSignaler) SendOffer(offer webrtc.SessionDescription) error {
+	return s.conn.Send(Message{Type: MessageTypeOffer, SDP: &offer})
+}
+
+func (s *connSignaler) SendAnswer(answer webrtc.SessionDescription) error {
+	return s.conn.Send(Message{Type: MessageTypeAnswer, SDP: &answer})
+}
+
+func (s *connSignaler) SendCandidate(c webrtc.ICECandidateInit) error {
+	return s.conn.Send(NewCandidateMessage(c))
+}
+
+func (s *connSignaler) SendState(state webrtc.PeerConnectionState) error {
+	return s.conn.Send(NewStateMessage(state))
+}
+
+func (s *connSignaler) Recv() <-chan Message {
+	return s.recv
+}
+
+func (s *connSignaler) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *connSignaler) Close() error {
+	err := error(nil)
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// memoryConn is one end of an in-memory MessageConn pair
+type memoryConn struct {
+	in, out chan Message
+	closed  chan struct{}
+	peer    *memoryConn
+	once    sync.Once
+}
+
+// NewMemorySignalers returns two connected in-memory Signalers, e.g. to test
+// offer and answer logic in a single process without any network
+func NewMemorySignalers() (Signaler, Signaler) {
+	a, b := newMemoryConns()
+	return NewConnSignaler(a), NewConnSignaler(b)
+}
+
+// memoryBuffer is how many messages a memoryConn holds for its peer
+const memoryBuffer = 64
+
+func newMemoryConns() (*memoryConn, *memoryConn) {
+	a2b, b2a := make(chan Message, memoryBuffer), make(chan Message, memoryBuffer)
+	a := &memoryConn{in: b2a, out: a2b, closed: make(chan struct{})}
+	b := &memoryConn{in: a2b, out: b2a, closed: make(chan struct{})}
+	a.peer, b.peer = b, a
+	return a, b
+}
+
+func (c *memoryConn) Send(msg Message) error {
+	// A closed end takes nothing, even while the buffer has room
+	select {
+	case <-c.closed:
+		return io.ErrClosedPipe
+	case <-c.peer.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	select {
+	case <-c.closed:
+		return io.ErrClosedPipe
+	case <-c.peer.closed:
+		return io.ErrClosedPipe
+	case c.out <- msg:
+		return nil
+	}
+}
+
+func (c *memoryConn) Recv() (Message, error) {
+	select {
+	case msg := <-c.in:
+		return msg, nil
+	case <-c.closed:
+		return Message{}, io.EOF
+	case <-c.peer.closed:
+		// Messages the peer sent before it closed are still delivered
+		select {
+		case msg := <-c.in:
+			return msg, nil
+		default:
+			return Message{}, io.EOF
+		}
+	}
+}
+
+// Close says bye unless the buffer of the peer is full, the peer sees io.EOF then
+func (c *memoryConn) Close() error {
+	select {
+	case c.out <- Message{Type: MessageTypeBye}:
+	default:
+	}
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+// SignalerOptions configures the transport of OpenSignaler
+type SignalerOptions struct {
+	// Transport is one of TransportHTTP, TransportWebSocket, TransportGRPC,
+	// TransportRedis, TransportStdio or TransportFile
+	Transport string
+	// ListenAddr is served by both peers over HTTP and by the answerer over WebSocket and gRPC
+	ListenAddr string
+	// RemoteAddr is the ListenAddr of the remote peer, over WebSocket and gRPC only the offerer needs it
+	RemoteAddr string
+	// RedisURL and SessionID select the Redis session
+	RedisURL  string
+	SessionID string
+	// Dir is the directory shared with the remote peer, SessionID picks the session in it
+	Dir string
+	// Config holds the credentials and TLS settings of HTTP, WebSocket and gRPC signaling
+	Config config.Config
+}
+
+// OpenSignaler opens the transport of opts for the peer playing role.
+// Over WebSocket and gRPC the answerer blocks until the offerer connected.
+func OpenSignaler(ctx context.Context, role Role, opts SignalerOptions) (Signaler, error) {
+	switch opts.Transport {
+	case TransportHTTP:
+		return openHTTPSignaler(opts)
+	case TransportWebSocket:
+		return openWebSocketSignaler(ctx, role, opts)
+	case TransportGRPC:
+		return openGRPCSignaler(ctx, role, opts)
+	case TransportRedis:
+		client, err := NewRedisClient(opts.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := DialRedis(ctx, client, opts.SessionID, role)
+		if err != nil {
+			return nil, err
+		}
+		return NewConnSignaler(conn), nil
+	case TransportStdio:
+		return NewConnSignaler(NewStdioConn(os.Stdin, os.Stdout)), nil
+	case TransportFile:
+		conn, err := DialFileDrop(opts.Dir, opts.SessionID, role)
+		if err != nil {
+			return nil, err
+		}
+		return NewConnSignaler(conn), nil
+	}
+	return nil, fmt.Errorf("%w %q", errUnknownTransport, opts.Transport)
+}
+
+func openHTTPSignaler(opts SignalerOptions) (Signaler, error) {
+	tlsConfig, err := ServerTLSConfig(opts.Config.TLS, opts.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewHTTPClient(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ListenHTTPConn(opts.ListenAddr, SignalingURL(opts.Config.TLS, "http", opts.RemoteAddr, HTTPConnPath),
+		client, NewAuthenticator(opts.Config), tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewConnSignaler(conn), nil
+}
+
+func openWebSocketSignaler(ctx context.Context, role Role, opts SignalerOptions) (Signaler, error) {
+	if role == RoleAnswer {
+		tlsConfig, err := ServerTLSConfig(opts.Config.TLS, opts.ListenAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case conn := <-StartWebSocketServer(opts.ListenAddr, NewAuthenticator(opts.Config), tlsConfig):
+			return NewConnSignaler(conn), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	dialer, err := NewWebSocketDialer(opts.Config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	url := SignalingURL(opts.Config.TLS, "ws", opts.RemoteAddr, "/ws")
+	header, err := AuthHeader(opts.Config, http.MethodGet, url)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := DialWebSocket(dialer, url, header)
+	if err != nil {
+		return nil, err
+	}
+	return NewConnSignaler(conn), nil
+}
+
+func openGRPCSignaler(ctx context.Context, role Role, opts SignalerOptions) (Signaler, error) {
+	if role == RoleAnswer {
+		tlsConfig, err := ServerTLSConfig(opts.Config.TLS, opts.ListenAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case conn := <-StartGRPCServer(opts.ListenAddr, NewAuthenticator(opts.Config), tlsConfig):
+			return NewConnSignaler(conn), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	dialOpts, err := GRPCDialOptions(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := DialGRPC(ctx, opts.RemoteAddr, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewConnSignaler(conn), nil
+}
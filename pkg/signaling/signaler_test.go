@@ -0,0 +1,100 @@
+package signaling
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func recvMessage(t *testing.T, s Signaler) Message {
+	t.Helper()
+
+	select {
+	case msg, ok := <-s.Recv():
+		if !ok {
+			t.Fatalf("signaler closed: %v", s.Err())
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a message")
+	}
+	return Message{}
+}
+
+func TestMemorySignalers(t *testing.T) {
+	a, b := NewMemorySignalers()
+
+	offer := webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "offer"}
+	answer := webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: "answer"}
+	candidate := webrtc.ICECandidateInit{Candidate: "candidate:1 1 udp 2130706431 192.0.2.1 50000 typ host"}
+
+	if err := a.SendOffer(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SendCandidate(candidate); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SendAnswer(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg := recvMessage(t, b); msg.Type != MessageTypeOffer || msg.SDP == nil || msg.SDP.SDP != offer.SDP {
+		t.Fatalf("got %+v, want the offer", msg)
+	}
+	if msg := recvMessage(t, b); msg.Type != MessageTypeCandidate || msg.Candidate == nil || msg.Candidate.Candidate != candidate.Candidate {
+		t.Fatalf("got %+v, want the candidate", msg)
+	}
+	if msg := recvMessage(t, a); msg.Type != MessageTypeAnswer || msg.SDP == nil || msg.SDP.SDP != answer.SDP {
+		t.Fatalf("got %+v, want the answer", msg)
+	}
+
+	// Close says bye, the remote Recv is closed after it without an error
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if msg := recvMessage(t, b); msg.Type != MessageTypeBye {
+		t.Fatalf("got %+v, want bye", msg)
+	}
+	if _, ok := <-b.Recv(); ok {
+		t.Fatal("Recv is still open after bye")
+	}
+	if err := b.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SendOffer(offer); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("send to a closed peer: got %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestMemoryConnCloseWithFullBuffer(t *testing.T) {
+	a, b := newMemoryConns()
+	for i := 0; i < memoryBuffer; i++ {
+		if err := a.Send(Message{Type: MessageTypeCandidate}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- a.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked on the full buffer of the peer")
+	}
+
+	// What was sent before Close is still delivered, then the peer sees io.EOF
+	for i := 0; i < memoryBuffer; i++ {
+		if _, err := b.Recv(); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+	}
+	if _, err := b.Recv(); !errors.Is(err, io.EOF) {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,5 @@
+// Package signalingpb holds the protobuf and gRPC stubs of signaling.proto,
+// the gRPC signaling service of package signaling.
+package signalingpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative signaling.proto
@@ -0,0 +1,148 @@
+// Wire contract of the gRPC signaling service of pkg/signaling, grpc.go serves
+// and dials it with the stubs generated into this package, see generate.go.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: signaling.proto
+
+package signalingpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Signaling_Negotiate_FullMethodName = "/signaling.v1.Signaling/Negotiate"
+)
+
+// SignalingClient is the client API for Signaling service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SignalingClient interface {
+	// Negotiate carries the signaling messages of one session in both directions.
+	// The client is the offerer, either side says bye before it closes the stream.
+	Negotiate(ctx context.Context, opts ...grpc.CallOption) (Signaling_NegotiateClient, error)
+}
+
+type signalingClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSignalingClient(cc grpc.ClientConnInterface) SignalingClient {
+	return &signalingClient{cc}
+}
+
+func (c *signalingClient) Negotiate(ctx context.Context, opts ...grpc.CallOption) (Signaling_NegotiateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Signaling_ServiceDesc.Streams[0], Signaling_Negotiate_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &signalingNegotiateClient{stream}
+	return x, nil
+}
+
+type Signaling_NegotiateClient interface {
+	Send(*Signal) error
+	Recv() (*Signal, error)
+	grpc.ClientStream
+}
+
+type signalingNegotiateClient struct {
+	grpc.ClientStream
+}
+
+func (x *signalingNegotiateClient) Send(m *Signal) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *signalingNegotiateClient) Recv() (*Signal, error) {
+	m := new(Signal)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SignalingServer is the server API for Signaling service.
+// All implementations must embed UnimplementedSignalingServer
+// for forward compatibility
+type SignalingServer interface {
+	// Negotiate carries the signaling messages of one session in both directions.
+	// The client is the offerer, either side says bye before it closes the stream.
+	Negotiate(Signaling_NegotiateServer) error
+	mustEmbedUnimplementedSignalingServer()
+}
+
+// UnimplementedSignalingServer must be embedded to have forward compatible implementations.
+type UnimplementedSignalingServer struct {
+}
+
+func (UnimplementedSignalingServer) Negotiate(Signaling_NegotiateServer) error {
+	return status.Errorf(codes.Unimplemented, "method Negotiate not implemented")
+}
+func (UnimplementedSignalingServer) mustEmbedUnimplementedSignalingServer() {}
+
+// UnsafeSignalingServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SignalingServer will
+// result in compilation errors.
+type UnsafeSignalingServer interface {
+	mustEmbedUnimplementedSignalingServer()
+}
+
+func RegisterSignalingServer(s grpc.ServiceRegistrar, srv SignalingServer) {
+	s.RegisterService(&Signaling_ServiceDesc, srv)
+}
+
+func _Signaling_Negotiate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SignalingServer).Negotiate(&signalingNegotiateServer{stream})
+}
+
+type Signaling_NegotiateServer interface {
+	Send(*Signal) error
+	Recv() (*Signal, error)
+	grpc.ServerStream
+}
+
+type signalingNegotiateServer struct {
+	grpc.ServerStream
+}
+
+func (x *signalingNegotiateServer) Send(m *Signal) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *signalingNegotiateServer) Recv() (*Signal, error) {
+	m := new(Signal)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Signaling_ServiceDesc is the grpc.ServiceDesc for Signaling service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Signaling_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "signaling.v1.Signaling",
+	HandlerType: (*SignalingServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Negotiate",
+			Handler:       _Signaling_Negotiate_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "signaling.proto",
+}
@@ -0,0 +1,77 @@
+package signaling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"webrtc-demo/pkg/signal"
+)
+
+// StdioConn exchanges Messages as pkg/signal blobs, one per line, e.g. over
+// stdin and stdout pasted by hand or piped through ssh. Armored chunks of
+// signal.Armor are reassembled, so long blobs may be pasted in pieces.
+type StdioConn struct {
+	r *signal.LineReader
+	// ctx is cancelled by Close, it ends a pending Recv
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	writeMu sync.Mutex
+	w       io.Writer
+}
+
+// NewStdioConn creates a StdioConn reading blobs from r and writing them to w
+func NewStdioConn(r io.Reader, w io.Writer) *StdioConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StdioConn{r: signal.NewLineReader(r), ctx: ctx, cancel: cancel, w: w}
+}
+
+// Send writes a Message as a single line blob
+func (c *StdioConn) Send(msg Message) error {
+	blob, err := signal.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err = fmt.Fprintln(c.w, blob)
+	return err
+}
+
+// Recv blocks until the next blob is read and decodes it.
+// Lines that are no blob, e.g. log output of a piped peer, are skipped.
+func (c *StdioConn) Recv() (Message, error) {
+	reassembler := signal.NewReassembler()
+
+	for {
+		line, err := c.r.ReadLine(c.ctx)
+		if errors.Is(err, context.Canceled) {
+			return Message{}, io.EOF
+		}
+		complete, feedErr := reassembler.Feed(line)
+		switch {
+		case feedErr != nil:
+			return Message{}, feedErr
+		case complete:
+			msg := Message{}
+			if err := signal.Unmarshal(reassembler.Blob(), &msg); err == nil {
+				return msg, nil
+			}
+			reassembler = signal.NewReassembler()
+		case err != nil:
+			return Message{}, err
+		}
+	}
+}
+
+// Close says bye to the remote side and ends a pending Recv with io.EOF,
+// the reader and writer stay open
+func (c *StdioConn) Close() error {
+	defer c.cancel()
+	return c.Send(Message{Type: MessageTypeBye})
+}
@@ -0,0 +1,70 @@
+package signaling
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"webrtc-demo/pkg/signal"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func TestStdioConn(t *testing.T) {
+	out := &bytes.Buffer{}
+	conn := NewStdioConn(strings.NewReader(""), out)
+
+	offer := webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: strings.Repeat("a=x\r\n", 200)}
+	if err := conn.Send(Message{Type: MessageTypeOffer, SDP: &offer}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want one blob per message", len(lines))
+	}
+
+	// The offer pasted as armored chunks, with log output of the peer around it
+	pasted := "peer starting\n" + strings.Join(signal.Armor(lines[0], 0), "") + "not a blob\n" + lines[1] + "\n"
+	remote := NewStdioConn(strings.NewReader(pasted), io.Discard)
+
+	msg, err := remote.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != MessageTypeOffer || msg.SDP == nil || msg.SDP.SDP != offer.SDP {
+		t.Fatalf("got %+v, want the offer", msg)
+	}
+	if msg, err := remote.Recv(); err != nil || msg.Type != MessageTypeBye {
+		t.Fatalf("got %+v, %v, want bye", msg, err)
+	}
+	if _, err := remote.Recv(); !errors.Is(err, io.EOF) {
+		t.Fatalf("got %v, want %v at the end of the input", err, io.EOF)
+	}
+}
+
+func TestStdioConnCloseEndsRecv(t *testing.T) {
+	pr, _ := io.Pipe()
+	t.Cleanup(func() { pr.Close() })
+
+	s := NewConnSignaler(NewStdioConn(pr, io.Discard))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case _, ok := <-s.Recv():
+		if ok {
+			t.Fatal("got a message from an empty stream")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Recv is still open after Close")
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,217 @@
+package signaling
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"webrtc-demo/pkg/config"
+
+	"github.com/gorilla/websocket"
+)
+
+// selfSignedValidity is how long a generated development certificate is valid
+const selfSignedValidity = 7 * 24 * time.Hour
+
+var (
+	errNoServerCertificate = errors.New("tls needs cert_file and key_file or self_signed")
+	errNoCACertificates    = errors.New("no CA certificates found")
+	errFingerprintMismatch = errors.New("certificate does not match the pinned fingerprint")
+)
+
+// ServerTLSConfig returns the TLS config of a signaling server listening on addr,
+// or nil when cfg does not enable TLS. A self-signed certificate is valid for
+// localhost, the loopback addresses and the host of addr, its fingerprint is logged
+// so clients can pin it.
+func ServerTLSConfig(cfg config.TLSConfig, addr string) (*tls.Config, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	var (
+		cert tls.Certificate
+		err  error
+	)
+	switch {
+	case cfg.CertFile != "" && cfg.KeyFile != "":
+		cert, err = tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	case cfg.SelfSigned:
+		host, _, _ := net.SplitHostPort(addr)
+		cert, err = SelfSignedCertificate(host)
+		if err == nil {
+			log.Printf("self-signed certificate, pin it with fingerprint = %q", CertificateFingerprint(cert.Certificate[0]))
+		}
+	default:
+		return nil, errNoServerCertificate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// SelfSignedCertificate generates an ECDSA certificate for development,
+// valid for localhost, 127.0.0.1, ::1 and hosts
+func SelfSignedCertificate(hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"webrtc-demo"}, CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of a DER certificate
+// in the colon separated hex form SDP uses, e.g. AB:CD:...
+func CertificateFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// ClientTLSConfig returns the TLS config clients use to reach a signaling server,
+// or nil to trust the system roots. CAFile defaults to CertFile so both sides can
+// share one config. A pinned fingerprint replaces the chain verification, so it
+// also works for self-signed certificates generated in memory.
+func ClientTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	if cfg.CAFile == "" {
+		cfg.CAFile = cfg.CertFile
+	}
+	if cfg.CAFile == "" && cfg.Fingerprint == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: %w", cfg.CAFile, errNoCACertificates)
+		}
+	}
+
+	if cfg.Fingerprint != "" {
+		pinned := normalizeFingerprint(cfg.Fingerprint)
+
+		// The chain is still verified against the CA file when one is given
+		tlsConfig.InsecureSkipVerify = cfg.CAFile == "" // nolint:gosec
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 ||
+				normalizeFingerprint(CertificateFingerprint(cs.PeerCertificates[0].Raw)) != pinned {
+				return errFingerprintMismatch
+			}
+			return nil
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// NewHTTPClient returns a client that trusts the server as configured in cfg.TLS
+// and authenticates every request with the credentials of cfg, see AuthTransport
+func NewHTTPClient(cfg config.Config) (*http.Client, error) {
+	tlsConfig, err := ClientTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: &AuthTransport{Config: cfg, Base: transport}}, nil
+}
+
+// NewWebSocketDialer returns a dialer that trusts the server as configured in cfg
+func NewWebSocketDialer(cfg config.TLSConfig) (*websocket.Dialer, error) {
+	tlsConfig, err := ClientTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = tlsConfig
+	return &dialer, nil
+}
+
+// SignalingURL returns the URL of path on a signaling server hosted on addr,
+// scheme "http" or "ws" is upgraded to "https" or "wss" when cfg enables TLS
+func SignalingURL(cfg config.TLSConfig, scheme, addr, path string) string {
+	if cfg.Enabled() {
+		scheme += "s"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, addr, path)
+}
+
+// ListenAndServe serves handler on addr, over TLS when tlsConfig is not nil
+func ListenAndServe(addr string, handler http.Handler, tlsConfig *tls.Config) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if tlsConfig == nil {
+		return server.ListenAndServe()
+	}
+	return server.ListenAndServeTLS("", "")
+}
+
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+}
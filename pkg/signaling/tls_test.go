@@ -0,0 +1,102 @@
+package signaling
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"webrtc-demo/pkg/config"
+)
+
+// startTLSServer serves an empty handler over tlsConfig
+func startTLSServer(t *testing.T, cfg config.TLSConfig) *httptest.Server {
+	t.Helper()
+
+	tlsConfig, err := ServerTLSConfig(cfg, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// getTLS GETs url with a client trusting the server as cfg says
+func getTLS(t *testing.T, cfg config.TLSConfig, url string) error {
+	t.Helper()
+
+	client, err := NewHTTPClient(config.Config{TLS: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestTLSFingerprintPinning(t *testing.T) {
+	server := startTLSServer(t, config.TLSConfig{SelfSigned: true})
+	fingerprint := CertificateFingerprint(server.TLS.Certificates[0].Certificate[0])
+
+	if err := getTLS(t, config.TLSConfig{Fingerprint: fingerprint}, server.URL); err != nil {
+		t.Fatalf("pinned fingerprint: %v", err)
+	}
+	if err := getTLS(t, config.TLSConfig{Fingerprint: "ab" + fingerprint[2:]}, server.URL); !errors.Is(err, errFingerprintMismatch) {
+		t.Fatalf("another fingerprint: got %v, want %v", err, errFingerprintMismatch)
+	}
+	if err := getTLS(t, config.TLSConfig{}, server.URL); err == nil {
+		t.Fatal("the system roots trust a self-signed certificate")
+	}
+}
+
+func TestTLSCAFile(t *testing.T) {
+	cert, err := SelfSignedCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(t.TempDir(), "cert.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tlsConfig, err := ClientTLSConfig(config.TLSConfig{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("CA file: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := ClientTLSConfig(config.TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Fatal("a missing CA file was taken")
+	}
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	if tlsConfig, err := ServerTLSConfig(config.TLSConfig{}, ":0"); tlsConfig != nil || err != nil {
+		t.Fatalf("got %v, %v without [tls], want neither", tlsConfig, err)
+	}
+	if _, err := ServerTLSConfig(config.TLSConfig{CAFile: "ca.pem"}, ":0"); !errors.Is(err, errNoServerCertificate) {
+		t.Fatalf("got %v, want %v", err, errNoServerCertificate)
+	}
+	if url := SignalingURL(config.TLSConfig{SelfSigned: true}, "ws", "localhost:8080", "/ws"); url != "wss://localhost:8080/ws" {
+		t.Fatalf("got %s, want wss://localhost:8080/ws", url)
+	}
+}
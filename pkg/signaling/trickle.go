@@ -0,0 +1,317 @@
+package signaling
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// EndOfCandidates is sent once the local side has gathered all of its candidates
+var EndOfCandidates = webrtc.ICECandidateInit{Candidate: ""}
+
+// IsEndOfCandidates reports whether c marks the end of the remote candidates
+func IsEndOfCandidates(c webrtc.ICECandidateInit) bool {
+	return c.Candidate == ""
+}
+
+// Trickle exchanges ICE candidates of a single PeerConnection.
+//
+// Local candidates are queued until the remote description is set and then
+// handed to send as complete ICECandidateInit objects (sdpMid, sdpMLineIndex and
+// usernameFragment included), which takes local descriptions to be set with
+// SetLocalDescription. Remote candidates that arrive before the remote
+// description are queued as well and applied right after it.
+type Trickle struct {
+	pc   *webrtc.PeerConnection
+	send func(webrtc.ICECandidateInit) error
+
+	mu    sync.Mutex
+	ready bool
+	// sending is set once local candidates may go out, which can be before ready
+	sending       bool
+	pendingLocal  []webrtc.ICECandidateInit
+	pendingRemote []webrtc.ICECandidateInit
+	// outbox holds the local candidates to send, flushing is set while one
+	// caller sends them without holding mu, in order
+	outbox   []webrtc.ICECandidateInit
+	flushing bool
+	// mid and ufrag of the local description, candidates are completed with them
+	mid   *string
+	ufrag *string
+
+	onError func(error)
+}
+
+// NewTrickle takes over OnICECandidate of pc and sends every local candidate with send
+func NewTrickle(pc *webrtc.PeerConnection, send func(webrtc.ICECandidateInit) error) *Trickle {
+	t := &Trickle{
+		pc:   pc,
+		send: send,
+		onError: func(err error) {
+			log.Println("cannot signal candidate:", err)
+		},
+	}
+	pc.OnICECandidate(t.onLocalCandidate)
+	return t
+}
+
+// OnError sets the handler for errors returned by send, by default they are logged
+func (t *Trickle) OnError(f func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.onError = f
+}
+
+func (t *Trickle) onLocalCandidate(c *webrtc.ICECandidate) {
+	// nil means gathering is complete
+	init := EndOfCandidates
+	if c != nil {
+		init = c.ToJSON()
+	}
+
+	t.mu.Lock()
+	init = t.completeCandidate(init)
+	if !t.sending {
+		t.pendingLocal = append(t.pendingLocal, init)
+		t.mu.Unlock()
+		return
+	}
+	t.outbox = append(t.outbox, init)
+	onError := t.onError
+	t.mu.Unlock()
+
+	if err := t.flush(); err != nil {
+		onError(err)
+	}
+}
+
+// completeCandidate fills in the fields ICECandidate.ToJSON leaves empty.
+// Pion gathers one set of candidates for the bundle, so they belong to the
+// first media section of the local description.
+//
+// It runs on the ICE agent, which PeerConnection.LocalDescription would wait
+// for, so the fields come from the description SetLocalDescription was given.
+func (t *Trickle) completeCandidate(init webrtc.ICECandidateInit) webrtc.ICECandidateInit {
+	if IsEndOfCandidates(init) {
+		return init
+	}
+	if t.mid != nil {
+		index := uint16(0)
+		init.SDPMid = t.mid
+		init.SDPMLineIndex = &index
+	}
+	if t.ufrag != nil {
+		init.UsernameFragment = t.ufrag
+	}
+	return init
+}
+
+// SetLocalDescription applies desc to the PeerConnection, which starts gathering.
+// The mid and ufrag of desc complete the local candidates gathered from now on.
+func (t *Trickle) SetLocalDescription(desc webrtc.SessionDescription) error {
+	mid, ufrag := bundleICE(desc)
+
+	t.mu.Lock()
+	t.mid, t.ufrag = mid, ufrag
+	t.mu.Unlock()
+
+	return t.pc.SetLocalDescription(desc)
+}
+
+// bundleICE returns the mid of the first media section of desc and its ufrag,
+// nil when desc has none
+func bundleICE(desc webrtc.SessionDescription) (mid, ufrag *string) {
+	parsed, err := desc.Unmarshal()
+	if err != nil {
+		return nil, nil
+	}
+
+	if value, ok := parsed.Attribute("ice-ufrag"); ok {
+		ufrag = &value
+	}
+	if len(parsed.MediaDescriptions) > 0 {
+		media := parsed.MediaDescriptions[0]
+		if value, ok := media.Attribute("mid"); ok {
+			mid = &value
+		}
+		if value, ok := media.Attribute("ice-ufrag"); ok && ufrag == nil {
+			ufrag = &value
+		}
+	}
+	return mid, ufrag
+}
+
+// SetRemoteDescription applies desc to the PeerConnection and flushes both queues
+func (t *Trickle) SetRemoteDescription(desc webrtc.SessionDescription) error {
+	if err := t.pc.SetRemoteDescription(desc); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.ready = true
+	t.sending = true
+
+	for _, c := range t.pendingRemote {
+		if err := t.pc.AddICECandidate(c); err != nil {
+			t.mu.Unlock()
+			return err
+		}
+	}
+	t.pendingRemote = nil
+	t.queuePending()
+	t.mu.Unlock()
+
+	return t.flush()
+}
+
+// SendLocal sends the queued local candidates and every later one right away,
+// while remote candidates still wait for the remote description. Call it once
+// the remote side has the local description, e.g. after the offer went out over
+// a transport that keeps messages in order.
+func (t *Trickle) SendLocal() error {
+	t.mu.Lock()
+	t.sending = true
+	t.queuePending()
+	t.mu.Unlock()
+
+	return t.flush()
+}
+
+// queuePending moves the held back local candidates to the outbox, t.mu must be held
+func (t *Trickle) queuePending() {
+	t.outbox = append(t.outbox, t.pendingLocal...)
+	t.pendingLocal = nil
+}
+
+// flush sends the outbox unless another call is at it already, that call
+// then sends the candidates queued meanwhile too. send runs without t.mu, so
+// a slow request holds up no other method. It returns the first error of send,
+// the candidates after it are sent all the same.
+func (t *Trickle) flush() error {
+	t.mu.Lock()
+	if t.flushing {
+		t.mu.Unlock()
+		return nil
+	}
+	t.flushing = true
+
+	var firstErr error
+	for len(t.outbox) > 0 {
+		batch := t.outbox
+		t.outbox = nil
+		t.mu.Unlock()
+
+		for _, c := range batch {
+			if err := t.send(c); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		t.mu.Lock()
+	}
+	t.flushing = false
+	t.mu.Unlock()
+
+	return firstErr
+}
+
+// RestartICE creates an offer with fresh ICE credentials, sets it as local
+// description and hands it to send. Candidates of both sides are held back
+// again until the answer is applied with SetRemoteDescription, which send may do.
+func (t *Trickle) RestartICE(send func(offer webrtc.SessionDescription) error) error {
+	// The restart gathers right away, before the remote side knows the new credentials
+	t.Hold()
+
+	offer, err := t.pc.CreateOffer(&webrtc.OfferOptions{ICERestart: true})
+	if err != nil {
+		return err
+	}
+	if err := t.SetLocalDescription(offer); err != nil {
+		return err
+	}
+	return send(offer)
+}
+
+// Hold queues candidates of both sides until the next SetRemoteDescription,
+// local candidates until SendLocal too
+func (t *Trickle) Hold() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.ready = false
+	t.sending = false
+}
+
+// AddRemoteCandidate applies a candidate received from the remote side,
+// or queues it when the remote description is not set yet
+func (t *Trickle) AddRemoteCandidate(c webrtc.ICECandidateInit) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.ready {
+		t.pendingRemote = append(t.pendingRemote, c)
+		return nil
+	}
+	return t.pc.AddICECandidate(c)
+}
+
+// PostCandidate sends c as JSON to a candidate handler listening on url.
+// client sends the request, e.g. one of NewHTTPClient that signs it.
+func PostCandidate(client *http.Client, url string, c webrtc.ICECandidateInit) error {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json; charset=utf-8", bytes.NewReader(payload)) // nolint:noctx
+	if err != nil {
+		return err
+	}
+	if closeErr := resp.Body.Close(); closeErr != nil {
+		return closeErr
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("candidate rejected: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// NewCandidateHandler returns a HTTP handler that feeds candidates sent by PostCandidate into t.
+// Bodies and the number of candidates are capped by DefaultLimits, a limit that
+// is not positive allows any number of candidates.
+func NewCandidateHandler(t *Trickle) http.HandlerFunc {
+	var mu sync.Mutex
+	received := 0
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		LimitBody(w, r, DefaultLimits.MaxBodyBytes)
+
+		c := webrtc.ICECandidateInit{}
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			http.Error(w, err.Error(), BodyErrorStatus(err))
+			return
+		}
+
+		mu.Lock()
+		received++
+		limit := DefaultLimits.MaxCandidatesPerSession
+		tooMany := limit > 0 && received > limit
+		mu.Unlock()
+		if tooMany {
+			http.Error(w, "too many candidates", http.StatusTooManyRequests)
+			return
+		}
+
+		if err := t.AddRemoteCandidate(c); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
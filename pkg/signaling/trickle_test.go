@@ -0,0 +1,202 @@
+package signaling
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// connectTimeout bounds how long a loopback pair may take to connect
+const connectTimeout = 15 * time.Second
+
+func newTestPeerConnection(t *testing.T) *webrtc.PeerConnection {
+	t.Helper()
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = pc.Close()
+	})
+	return pc
+}
+
+// connected is closed once pc is connected, it takes over OnConnectionStateChange
+func connected(pc *webrtc.PeerConnection) <-chan struct{} {
+	done := make(chan struct{})
+	var once sync.Once
+	pc.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		if s == webrtc.PeerConnectionStateConnected {
+			once.Do(func() { close(done) })
+		}
+	})
+	return done
+}
+
+func waitFor(t *testing.T, done <-chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-done:
+	case <-time.After(connectTimeout):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestTrickleLoopback(t *testing.T) {
+	offerer, answerer := newTestPeerConnection(t), newTestPeerConnection(t)
+	offererConnected, answererConnected := connected(offerer), connected(answerer)
+
+	var mu sync.Mutex
+	sent := []webrtc.ICECandidateInit{}
+
+	// Candidates are handed over from goroutines, like over a network, so
+	// neither side waits for the other inside OnICECandidate
+	var offerTrickle, answerTrickle *Trickle
+	offerTrickle = NewTrickle(offerer, func(c webrtc.ICECandidateInit) error {
+		mu.Lock()
+		sent = append(sent, c)
+		mu.Unlock()
+		go func() { _ = answerTrickle.AddRemoteCandidate(c) }()
+		return nil
+	})
+	answerTrickle = NewTrickle(answerer, func(c webrtc.ICECandidateInit) error {
+		go func() { _ = offerTrickle.AddRemoteCandidate(c) }()
+		return nil
+	})
+
+	if _, err := offerer.CreateDataChannel("data", nil); err != nil {
+		t.Fatal(err)
+	}
+	offer, err := offerer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := offerTrickle.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err := answerTrickle.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := answerer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := answerTrickle.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err := offerTrickle.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, offererConnected, "the offerer to connect")
+	waitFor(t, answererConnected, "the answerer to connect")
+
+	mid, ufrag := bundleICE(offer)
+	if mid == nil || ufrag == nil {
+		t.Fatal("offer without mid or ice-ufrag")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) == 0 {
+		t.Fatal("no candidate was sent")
+	}
+	for _, c := range sent {
+		if IsEndOfCandidates(c) {
+			continue
+		}
+		if c.SDPMid == nil || *c.SDPMid != *mid || c.SDPMLineIndex == nil || *c.SDPMLineIndex != 0 {
+			t.Errorf("candidate %q: want sdpMid %q and sdpMLineIndex 0", c.Candidate, *mid)
+		}
+		if c.UsernameFragment == nil || *c.UsernameFragment != *ufrag {
+			t.Errorf("candidate %q: want usernameFragment %q", c.Candidate, *ufrag)
+		}
+	}
+}
+
+func TestTrickleSendsWithoutLock(t *testing.T) {
+	pc := newTestPeerConnection(t)
+	if _, err := pc.CreateDataChannel("data", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// send is stuck like a request to an unresponsive server until release
+	blocked, release := make(chan struct{}, 1), make(chan struct{})
+	var releaseOnce sync.Once
+	releaseAll := func() { releaseOnce.Do(func() { close(release) }) }
+	t.Cleanup(releaseAll)
+
+	var mu sync.Mutex
+	sent := []webrtc.ICECandidateInit{}
+	gathered := make(chan struct{})
+	trickle := NewTrickle(pc, func(c webrtc.ICECandidateInit) error {
+		select {
+		case blocked <- struct{}{}:
+		default:
+		}
+		<-release
+
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, c)
+		if IsEndOfCandidates(c) {
+			close(gathered)
+		}
+		return nil
+	})
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := trickle.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = trickle.SendLocal() }()
+	waitFor(t, blocked, "the first candidate to be sent")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = trickle.AddRemoteCandidate(webrtc.ICECandidateInit{Candidate: "candidate:1 1 udp 2130706431 192.0.2.1 50000 typ host"})
+		trickle.Hold()
+		_ = trickle.SendLocal()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Trickle is locked while send is blocked")
+	}
+
+	releaseAll()
+	waitFor(t, gathered, "gathering")
+
+	// The end of candidates still comes last
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) < 2 || !IsEndOfCandidates(sent[len(sent)-1]) {
+		t.Fatalf("got %+v, want candidates and the end of candidates last", sent)
+	}
+}
+
+func TestCandidateHandlerUnlimited(t *testing.T) {
+	limits := DefaultLimits
+	t.Cleanup(func() { DefaultLimits = limits })
+	DefaultLimits.MaxCandidatesPerSession = 0
+
+	handler := NewCandidateHandler(NewTrickle(newTestPeerConnection(t), func(webrtc.ICECandidateInit) error { return nil }))
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, "/candidate", strings.NewReader(`{"candidate":"candidate:1 1 udp 2130706431 192.0.2.1 50000 typ host"}`)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("candidate %d: got %d, want %d without a limit", i, w.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package signaling
+
+import (
+	"crypto/tls"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+)
+
+const wsCloseTimeout = time.Second
+
+var upgrader = websocket.Upgrader{
+	// Demos are served to anyone on the network, origin is not checked.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// WebSocketConn is a persistent signaling socket carrying typed Messages
+// in both directions.
+type WebSocketConn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// DialWebSocket connects to a WebSocket signaling server, e.g. ws://localhost:8080/ws or wss://.
+// dialer opens the socket, e.g. one of NewWebSocketDialer that trusts a CA file or pinned fingerprint.
+// header is sent with the handshake, it may carry an Authorization from AuthHeader.
+func DialWebSocket(dialer *websocket.Dialer, url string, header http.Header) (*WebSocketConn, error) {
+	conn, _, err := dialer.Dial(url, header)
+	if err != nil {
+		return nil, err
+	}
+	return &WebSocketConn{conn: conn}, nil
+}
+
+// NewWebSocketHandler upgrades every request to a WebSocketConn and hands it to onConn.
+// Messages larger than DefaultLimits.MaxBodyBytes close the socket.
+func NewWebSocketHandler(onConn func(*WebSocketConn)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Println("cannot upgrade signaling connection:", err)
+			return
+		}
+		if DefaultLimits.MaxBodyBytes > 0 {
+			conn.SetReadLimit(DefaultLimits.MaxBodyBytes)
+		}
+		onConn(&WebSocketConn{conn: conn})
+	}
+}
+
+// StartWebSocketServer serves GET /ws on addr and returns accepted connections.
+// Handshakes are checked by authenticator, a nil authenticator accepts everyone,
+// and rate limited by DefaultLimits. The socket is wss:// when tlsConfig is not nil, see ServerTLSConfig.
+func StartWebSocketServer(addr string, authenticator *Authenticator, tlsConfig *tls.Config) chan *WebSocketConn {
+	connChan := make(chan *WebSocketConn, 1)
+
+	router := httprouter.New()
+	router.Handler(http.MethodGet, "/ws", DefaultLimits.Middleware(authenticator.Middleware(NewWebSocketHandler(func(c *WebSocketConn) {
+		connChan <- c
+	}))))
+
+	go func() {
+		if err := ListenAndServe(addr, router, tlsConfig); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	return connChan
+}
+
+// Send writes a Message to the socket, it is safe for concurrent use
+func (c *WebSocketConn) Send(msg Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.conn.WriteJSON(msg)
+}
+
+// Recv blocks until the next Message arrives
+func (c *WebSocketConn) Recv() (Message, error) {
+	msg := Message{}
+	err := c.conn.ReadJSON(&msg)
+	return msg, err
+}
+
+// Close says bye to the remote side and closes the socket
+func (c *WebSocketConn) Close() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.WriteJSON(Message{Type: MessageTypeBye})
+	_ = c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsCloseTimeout))
+
+	return c.conn.Close()
+}
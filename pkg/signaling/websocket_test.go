@@ -0,0 +1,78 @@
+package signaling
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pion/webrtc/v3"
+)
+
+// dialWebSocket serves NewWebSocketHandler and dials it, it returns both ends
+func dialWebSocket(t *testing.T) (client, server *WebSocketConn) {
+	t.Helper()
+
+	conns := make(chan *WebSocketConn, 1)
+	srv := httptest.NewServer(NewWebSocketHandler(func(c *WebSocketConn) {
+		conns <- c
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := DialWebSocket(websocket.DefaultDialer, "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case server = <-conns:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server got no connection")
+	}
+	return client, server
+}
+
+func TestWebSocketConn(t *testing.T) {
+	client, server := dialWebSocket(t)
+	a, b := NewConnSignaler(client), NewConnSignaler(server)
+
+	offer := webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "offer"}
+	if err := a.SendOffer(offer); err != nil {
+		t.Fatal(err)
+	}
+	if msg := recvMessage(t, b); msg.Type != MessageTypeOffer || msg.SDP == nil || *msg.SDP != offer {
+		t.Fatalf("got %+v, want the offer", msg)
+	}
+
+	mid := "0"
+	candidate := webrtc.ICECandidateInit{Candidate: "candidate:1 1 udp 2130706431 127.0.0.1 5000 typ host", SDPMid: &mid}
+	if err := b.SendCandidate(candidate); err != nil {
+		t.Fatal(err)
+	}
+	if msg := recvMessage(t, a); msg.Type != MessageTypeCandidate || msg.Candidate == nil || msg.Candidate.Candidate != candidate.Candidate {
+		t.Fatalf("got %+v, want the candidate", msg)
+	}
+
+	// Close says bye, the other side ends without an error
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if msg := recvMessage(t, b); msg.Type != MessageTypeBye {
+		t.Fatalf("got %+v, want bye", msg)
+	}
+	if _, ok := <-b.Recv(); ok || b.Err() != nil {
+		t.Fatalf("Recv stays open or failed with %v after bye", b.Err())
+	}
+}
+
+func TestWebSocketConnReadLimit(t *testing.T) {
+	client, server := dialWebSocket(t)
+
+	sdp := strings.Repeat("a", int(DefaultLimits.MaxBodyBytes))
+	if err := client.Send(Message{Type: MessageTypeOffer, SDP: &webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: sdp}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Recv(); err == nil {
+		t.Fatal("a message over MaxBodyBytes was read")
+	}
+}
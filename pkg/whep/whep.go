@@ -0,0 +1,60 @@
+// Package whep implements both ends of the WebRTC-HTTP Egress Protocol.
+//
+// WHEP shares the HTTP resource model of WHIP: the player POSTs a recvonly
+// offer, trickles candidates with PATCH and ends the session with DELETE.
+// Only the direction of the media differs, so both ends are built on pkg/whip.
+package whep
+
+import (
+	"net/http"
+
+	"webrtc-demo/pkg/whip"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Client plays a single stream from a WHEP endpoint
+type Client struct {
+	resource *whip.Client
+}
+
+// NewClient creates a Client for endpoint, e.g. http://localhost:8080/whep
+func NewClient(endpoint, token string) *Client {
+	return &Client{resource: whip.NewClient(endpoint, token)}
+}
+
+// SetHTTPClient replaces the client the requests are sent with, e.g. to trust a self-signed certificate
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.resource.HTTPClient = httpClient
+}
+
+// Location returns the URL of the session, it is empty until Subscribe succeeds
+func (c *Client) Location() string {
+	return c.resource.Location()
+}
+
+// Subscribe POSTs a recvonly offer and returns the answer of the WHEP server
+func (c *Client) Subscribe(offer webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	return c.resource.Publish(offer)
+}
+
+// Trickle PATCHes candidates to the session as a trickle ICE SDP fragment
+func (c *Client) Trickle(candidates ...webrtc.ICECandidateInit) error {
+	return c.resource.Trickle(candidates...)
+}
+
+// RestartICE PATCHes the credentials of an ICE restart offer and returns the answer to apply
+func (c *Client) RestartICE(offer, remote webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	return c.resource.RestartICE(offer, remote)
+}
+
+// Close DELETEs the session, it is a no-op when Subscribe did not succeed
+func (c *Client) Close() error {
+	return c.resource.Close()
+}
+
+// NewServer creates a WHEP endpoint serving POST /whep, PATCH /whep/:id and DELETE /whep/:id.
+// onSession adds the tracks to play to every new session.
+func NewServer(config webrtc.Configuration, onSession func(id string, pc *webrtc.PeerConnection) error) *whip.Server {
+	return whip.NewServerWithPath("/whep", config, onSession)
+}
@@ -0,0 +1,202 @@
+// Package whip implements both ends of the WebRTC-HTTP Ingestion Protocol.
+//
+// The client POSTs an SDP offer to the WHIP endpoint and gets the answer back
+// together with the URL of the created session in the Location header.
+// Trickled candidates are PATCHed to that URL and a DELETE ends the session.
+package whip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const sdpContentType = "application/sdp"
+
+var (
+	errNoLocation    = errors.New("whip: response has no Location header")
+	errNoSession     = errors.New("whip: no session, publish first")
+	errNoCredentials = errors.New("whip: no ice-ufrag and ice-pwd")
+)
+
+// Client publishes a single stream to a WHIP endpoint
+type Client struct {
+	Endpoint string
+	// Token is sent as a bearer token when it is not empty
+	Token      string
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	location string
+}
+
+// NewClient creates a Client for endpoint, e.g. http://localhost:8080/whip
+func NewClient(endpoint, token string) *Client {
+	return &Client{
+		Endpoint:   endpoint,
+		Token:      token,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Location returns the URL of the session, it is empty until Publish succeeds
+func (c *Client) Location() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.location
+}
+
+// Publish POSTs the offer and returns the answer of the WHIP server
+func (c *Client) Publish(offer webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	answer := webrtc.SessionDescription{}
+
+	resp, err := c.do(http.MethodPost, c.Endpoint, sdpContentType, []byte(offer.SDP))
+	if err != nil {
+		return answer, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return answer, err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return answer, fmt.Errorf("whip: unexpected status %s: %s", resp.Status, body)
+	}
+
+	location, err := resolveLocation(c.Endpoint, resp.Header.Get("Location"))
+	if err != nil {
+		return answer, err
+	}
+
+	c.mu.Lock()
+	c.location = location
+	c.mu.Unlock()
+
+	answer.Type = webrtc.SDPTypeAnswer
+	answer.SDP = string(body)
+	return answer, nil
+}
+
+// Trickle PATCHes candidates to the session as a trickle ICE SDP fragment
+func (c *Client) Trickle(candidates ...webrtc.ICECandidateInit) error {
+	location := c.Location()
+	if location == "" {
+		return errNoSession
+	}
+
+	resp, err := c.do(http.MethodPatch, location, SDPFragmentContentType, []byte(MarshalSDPFragment(candidates)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("whip: trickle rejected: %s", resp.Status)
+	}
+	return nil
+}
+
+// RestartICE PATCHes the credentials of offer, an ICE restart offer that is set
+// as local description already, and returns the answer to apply: remote, the
+// current remote description, with the new credentials and candidates of the server.
+// Local candidates are trickled afterwards.
+func (c *Client) RestartICE(offer, remote webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	location := c.Location()
+	if location == "" {
+		return remote, errNoSession
+	}
+	creds, ok := ParseICECredentials(offer.SDP)
+	if !ok {
+		return remote, errNoCredentials
+	}
+
+	resp, err := c.do(http.MethodPatch, location, SDPFragmentContentType, []byte(MarshalICERestart(creds, nil)))
+	if err != nil {
+		return remote, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return remote, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return remote, fmt.Errorf("whip: ice restart rejected: %s: %s", resp.Status, body)
+	}
+
+	serverCreds, ok := ParseICECredentials(string(body))
+	if !ok {
+		return remote, errNoCredentials
+	}
+	candidates, err := UnmarshalSDPFragment(string(body))
+	if err != nil && !errors.Is(err, errNoFragmentCandidates) {
+		return remote, err
+	}
+
+	answer, err := WithICECredentials(remote, serverCreds, candidates)
+	answer.Type = webrtc.SDPTypeAnswer
+	return answer, err
+}
+
+// Close DELETEs the session, it is a no-op when Publish did not succeed
+func (c *Client) Close() error {
+	location := c.Location()
+	if location == "" {
+		return nil
+	}
+
+	resp, err := c.do(http.MethodDelete, location, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	c.mu.Lock()
+	c.location = ""
+	c.mu.Unlock()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("whip: delete rejected: %s", resp.Status)
+	}
+	return nil
+}
+
+func (c *Client) do(method, url, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body)) // nolint:noctx
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	return c.HTTPClient.Do(req)
+}
+
+// resolveLocation makes a possibly relative Location header absolute
+func resolveLocation(endpoint, location string) (string, error) {
+	if location == "" {
+		return "", errNoLocation
+	}
+
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
@@ -0,0 +1,210 @@
+package whip
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+)
+
+// SDPFragmentContentType is the media type of trickle ICE PATCH bodies (RFC 8840)
+const SDPFragmentContentType = "application/trickle-ice-sdpfrag"
+
+var errNoFragmentCandidates = errors.New("sdp fragment carries no candidates")
+
+// MarshalSDPFragment turns candidates into a trickle ICE SDP fragment.
+// An empty candidate is written as a=end-of-candidates.
+func MarshalSDPFragment(candidates []webrtc.ICECandidateInit) string {
+	var b strings.Builder
+
+	wroteUfrag := false
+	lastMid := ""
+	wroteMedia := false
+	for _, c := range candidates {
+		if !wroteUfrag && c.UsernameFragment != nil && *c.UsernameFragment != "" {
+			b.WriteString("a=ice-ufrag:" + *c.UsernameFragment + "\r\n")
+			wroteUfrag = true
+		}
+
+		mid := ""
+		if c.SDPMid != nil {
+			mid = *c.SDPMid
+		}
+		if !wroteMedia || mid != lastMid {
+			b.WriteString("m=audio 9 RTP/AVP 0\r\n")
+			b.WriteString("a=mid:" + mid + "\r\n")
+			lastMid, wroteMedia = mid, true
+		}
+
+		if c.Candidate == "" {
+			b.WriteString("a=end-of-candidates\r\n")
+			continue
+		}
+		b.WriteString("a=" + strings.TrimPrefix(c.Candidate, "a=") + "\r\n")
+	}
+
+	return b.String()
+}
+
+// UnmarshalSDPFragment parses a trickle ICE SDP fragment back into candidates.
+// a=end-of-candidates is returned as a candidate with an empty Candidate field.
+func UnmarshalSDPFragment(frag string) ([]webrtc.ICECandidateInit, error) {
+	var (
+		candidates []webrtc.ICECandidateInit
+		ufrag      *string
+		mid        *string
+		index      *uint16
+		mLines     uint16
+	)
+
+	for _, line := range strings.Split(frag, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "a=ice-ufrag:"):
+			u := strings.TrimPrefix(line, "a=ice-ufrag:")
+			ufrag = &u
+		case strings.HasPrefix(line, "m="):
+			i := mLines
+			index = &i
+			mid = nil
+			mLines++
+		case strings.HasPrefix(line, "a=mid:"):
+			m := strings.TrimPrefix(line, "a=mid:")
+			mid = &m
+		case strings.HasPrefix(line, "a=candidate:"):
+			candidates = append(candidates, webrtc.ICECandidateInit{
+				Candidate:        strings.TrimPrefix(line, "a="),
+				SDPMid:           mid,
+				SDPMLineIndex:    index,
+				UsernameFragment: ufrag,
+			})
+		case line == "a=end-of-candidates":
+			candidates = append(candidates, webrtc.ICECandidateInit{
+				SDPMid:           mid,
+				SDPMLineIndex:    index,
+				UsernameFragment: ufrag,
+			})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, errNoFragmentCandidates
+	}
+	return candidates, nil
+}
+
+// ICECredentials are the ice-ufrag and ice-pwd of one end of a session
+type ICECredentials struct {
+	Ufrag string
+	Pwd   string
+}
+
+// ParseICECredentials returns the first ice-ufrag and ice-pwd of an SDP fragment
+// or description, ok is false unless both are present. A fragment with both
+// asks for an ICE restart, plain trickle fragments carry the ufrag only.
+func ParseICECredentials(frag string) (creds ICECredentials, ok bool) {
+	for _, line := range strings.Split(frag, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "a=ice-ufrag:") && creds.Ufrag == "":
+			creds.Ufrag = strings.TrimPrefix(line, "a=ice-ufrag:")
+		case strings.HasPrefix(line, "a=ice-pwd:") && creds.Pwd == "":
+			creds.Pwd = strings.TrimPrefix(line, "a=ice-pwd:")
+		}
+	}
+	return creds, creds.Ufrag != "" && creds.Pwd != ""
+}
+
+// MarshalICERestart turns the new credentials of an ICE restart and the
+// candidates gathered with them into a trickle ICE SDP fragment
+func MarshalICERestart(creds ICECredentials, candidates []webrtc.ICECandidateInit) string {
+	header := "a=ice-ufrag:" + creds.Ufrag + "\r\n" + "a=ice-pwd:" + creds.Pwd + "\r\n"
+
+	// The credentials are in the header, do not repeat them
+	stripped := make([]webrtc.ICECandidateInit, len(candidates))
+	for i, c := range candidates {
+		c.UsernameFragment = nil
+		stripped[i] = c
+	}
+	return header + MarshalSDPFragment(stripped)
+}
+
+// Candidates returns the candidates of the first media section of desc, the
+// bundle transport of Pion, followed by end-of-candidates when gathering is complete
+func Candidates(desc webrtc.SessionDescription) ([]webrtc.ICECandidateInit, error) {
+	parsed := sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(desc.SDP)); err != nil {
+		return nil, err
+	}
+
+	candidates := []webrtc.ICECandidateInit{}
+	if len(parsed.MediaDescriptions) == 0 {
+		return candidates, nil
+	}
+
+	media := parsed.MediaDescriptions[0]
+	mid, _ := media.Attribute("mid")
+	index := uint16(0)
+	for _, attr := range media.Attributes {
+		if attr.Key == "candidate" {
+			candidates = append(candidates, webrtc.ICECandidateInit{
+				Candidate:     "candidate:" + attr.Value,
+				SDPMid:        &mid,
+				SDPMLineIndex: &index,
+			})
+		}
+	}
+	if _, complete := media.Attribute("end-of-candidates"); complete {
+		candidates = append(candidates, webrtc.ICECandidateInit{SDPMid: &mid, SDPMLineIndex: &index})
+	}
+
+	return candidates, nil
+}
+
+// WithICECredentials returns desc after an ICE restart of the remote end: every
+// ice-ufrag and ice-pwd is replaced with creds and the candidates with candidates,
+// which are put in the first media section, the bundle transport of Pion
+func WithICECredentials(desc webrtc.SessionDescription, creds ICECredentials, candidates []webrtc.ICECandidateInit) (webrtc.SessionDescription, error) {
+	parsed := sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(desc.SDP)); err != nil {
+		return desc, err
+	}
+
+	replace := func(attrs []sdp.Attribute) []sdp.Attribute {
+		kept := make([]sdp.Attribute, 0, len(attrs))
+		for _, attr := range attrs {
+			switch attr.Key {
+			case "candidate", "end-of-candidates":
+				continue
+			case "ice-ufrag":
+				attr.Value = creds.Ufrag
+			case "ice-pwd":
+				attr.Value = creds.Pwd
+			}
+			kept = append(kept, attr)
+		}
+		return kept
+	}
+
+	parsed.Attributes = replace(parsed.Attributes)
+	for i, media := range parsed.MediaDescriptions {
+		media.Attributes = replace(media.Attributes)
+		if i != 0 {
+			continue
+		}
+		for _, c := range candidates {
+			if c.Candidate == "" {
+				media.WithPropertyAttribute("end-of-candidates")
+				continue
+			}
+			media.WithValueAttribute("candidate", strings.TrimPrefix(strings.TrimPrefix(c.Candidate, "a="), "candidate:"))
+		}
+	}
+
+	out, err := parsed.Marshal()
+	if err != nil {
+		return desc, err
+	}
+	return webrtc.SessionDescription{Type: desc.Type, SDP: string(out)}, nil
+}
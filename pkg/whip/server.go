@@ -0,0 +1,362 @@
+package whip
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"webrtc-demo/pkg/signal"
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pion/webrtc/v3"
+)
+
+// Server is a minimal WHIP endpoint serving POST /whip, PATCH /whip/:id and DELETE /whip/:id.
+//
+// WHEP uses the same resource model, NewServerWithPath serves it under another path.
+// Each POST gets its own PeerConnection. The server waits for ICE gathering to
+// complete before answering, so its own candidates are always in the answer and
+// only the publisher trickles. A PATCH with a new ice-ufrag and ice-pwd restarts
+// ICE, it is answered with the new credentials and candidates of the server.
+//
+// Bodies, trickled candidates per session and the number of sessions are bounded
+// by signaling.DefaultLimits, see SetLimits, and sessions that do not connect
+// within its ConnectTimeout are closed. Rate limits are up to the caller, e.g.
+// signaling.Limits.Middleware.
+type Server struct {
+	path   string
+	config webrtc.Configuration
+	// api creates the session PeerConnections, webrtc.NewPeerConnection when nil
+	api    *webrtc.API
+	limits signaling.Limits
+	// onSession is called before the offer is applied, register OnTrack or
+	// add tracks here. Returning an error rejects the session.
+	// The server owns OnConnectionStateChange to drop closed sessions.
+	onSession func(id string, pc *webrtc.PeerConnection) error
+	// onSessionClosed is called once the PeerConnection of a session closed,
+	// the server closes failed sessions
+	onSessionClosed func(id string, pc *webrtc.PeerConnection)
+
+	mu       sync.Mutex
+	sessions map[string]*webrtc.PeerConnection
+	// candidates counts the trickled candidates of each session
+	candidates map[string]int
+
+	router *httprouter.Router
+}
+
+// NewServer creates a WHIP Server, config is used for every session PeerConnection
+func NewServer(config webrtc.Configuration, onSession func(id string, pc *webrtc.PeerConnection) error) *Server {
+	return NewServerWithPath("/whip", config, onSession)
+}
+
+// NewServerWithPath creates a Server that creates sessions on POST path
+func NewServerWithPath(path string, config webrtc.Configuration, onSession func(id string, pc *webrtc.PeerConnection) error) *Server {
+	s := &Server{
+		path:       path,
+		config:     config,
+		onSession:  onSession,
+		limits:     signaling.DefaultLimits,
+		sessions:   map[string]*webrtc.PeerConnection{},
+		candidates: map[string]int{},
+		router:     httprouter.New(),
+	}
+
+	s.router.POST(path, s.handleOffer)
+	s.router.PATCH(path+"/:id", s.handleTrickle)
+	s.router.DELETE(path+"/:id", s.handleDelete)
+
+	return s
+}
+
+// SetAPI creates every session PeerConnection with api, e.g. one with a SettingEngine
+func (s *Server) SetAPI(api *webrtc.API) {
+	s.api = api
+}
+
+// OnSessionClosed sets a handler for sessions whose PeerConnection closed, whether
+// by DELETE, after failing or because the offer was rejected, e.g. to release
+// what onSession took for them
+func (s *Server) OnSessionClosed(f func(id string, pc *webrtc.PeerConnection)) {
+	s.onSessionClosed = f
+}
+
+// SetLimits replaces signaling.DefaultLimits, call it before serving.
+// The rate limits are ignored, they are up to signaling.Limits.Middleware.
+func (s *Server) SetLimits(limits signaling.Limits) {
+	s.limits = limits
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+func (s *Server) newPeerConnection() (*webrtc.PeerConnection, error) {
+	if s.api == nil {
+		return webrtc.NewPeerConnection(s.config)
+	}
+	return s.api.NewPeerConnection(s.config)
+}
+
+func (s *Server) handleOffer(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !hasContentType(r, sdpContentType) {
+		http.Error(w, "expected "+sdpContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	signaling.LimitBody(w, r, s.limits.MaxBodyBytes)
+	offer, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), signaling.BodyErrorStatus(err))
+		return
+	}
+	if s.full() {
+		http.Error(w, signaling.ErrTooManySessions.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	peerConnection, err := s.newPeerConnection()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	id := signal.RandSeq(16)
+	connected := atomic.Bool{}
+	peerConnection.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		log.Printf("%s session %s: %s\n", s.path, id, state)
+
+		switch state {
+		case webrtc.PeerConnectionStateConnected:
+			connected.Store(true)
+		case webrtc.PeerConnectionStateFailed:
+			if failed := s.remove(id); failed != nil {
+				go closePeerConnection(failed)
+			}
+		case webrtc.PeerConnectionStateClosed:
+			s.remove(id)
+			if s.onSessionClosed != nil {
+				s.onSessionClosed(id, peerConnection)
+			}
+		}
+	})
+	if s.onSession != nil {
+		if err := s.onSession(id, peerConnection); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			closePeerConnection(peerConnection)
+			return
+		}
+	}
+
+	answer, err := answerOffer(peerConnection, string(offer))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		closePeerConnection(peerConnection)
+		return
+	}
+
+	if !s.add(id, peerConnection) {
+		http.Error(w, signaling.ErrTooManySessions.Error(), http.StatusServiceUnavailable)
+		closePeerConnection(peerConnection)
+		return
+	}
+	if s.limits.ConnectTimeout > 0 {
+		time.AfterFunc(s.limits.ConnectTimeout, func() {
+			if !connected.Load() && s.remove(id) != nil {
+				log.Printf("%s session %s: not connected after %s\n", s.path, id, s.limits.ConnectTimeout)
+				closePeerConnection(peerConnection)
+			}
+		})
+	}
+
+	w.Header().Set("Content-Type", sdpContentType)
+	w.Header().Set("Location", s.path+"/"+id)
+	w.WriteHeader(http.StatusCreated)
+	if _, err := io.WriteString(w, answer.SDP); err != nil {
+		log.Println("cannot write answer:", err)
+	}
+}
+
+func (s *Server) handleTrickle(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	peerConnection := s.get(p.ByName("id"))
+	if peerConnection == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !hasContentType(r, SDPFragmentContentType) {
+		http.Error(w, "expected "+SDPFragmentContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	signaling.LimitBody(w, r, s.limits.MaxBodyBytes)
+	frag, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), signaling.BodyErrorStatus(err))
+		return
+	}
+
+	if creds, ok := ParseICECredentials(string(frag)); ok {
+		if remote, _ := ParseICECredentials(peerConnection.RemoteDescription().SDP); creds != remote {
+			s.handleICERestart(w, peerConnection, creds, string(frag))
+			return
+		}
+	}
+
+	candidates, err := UnmarshalSDPFragment(string(frag))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.countCandidates(p.ByName("id"), len(candidates)) {
+		http.Error(w, "too many candidates for this session", http.StatusTooManyRequests)
+		return
+	}
+
+	for _, c := range candidates {
+		if err := peerConnection.AddICECandidate(c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleICERestart applies the new credentials of the client as a new offer
+// and returns the credentials and candidates of the answer (RFC 9725)
+func (s *Server) handleICERestart(w http.ResponseWriter, peerConnection *webrtc.PeerConnection, creds ICECredentials, frag string) {
+	// A restart may come without candidates, they are trickled afterwards
+	candidates, err := UnmarshalSDPFragment(frag)
+	if err != nil && !errors.Is(err, errNoFragmentCandidates) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	offer, err := WithICECredentials(*peerConnection.RemoteDescription(), creds, candidates)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	answer, err := answerOffer(peerConnection, offer.SDP)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	local, _ := ParseICECredentials(answer.SDP)
+	localCandidates, err := Candidates(*answer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", SDPFragmentContentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.WriteString(w, MarshalICERestart(local, localCandidates)); err != nil {
+		log.Println("cannot write ice restart:", err)
+	}
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	peerConnection := s.remove(p.ByName("id"))
+	if peerConnection == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := peerConnection.Close(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// full reports whether there are Limits.MaxSessions sessions already
+func (s *Server) full() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.limits.MaxSessions > 0 && len(s.sessions) >= s.limits.MaxSessions
+}
+
+// add stores a new session unless there are Limits.MaxSessions already, offers
+// are answered concurrently so full may have let more through
+func (s *Server) add(id string, peerConnection *webrtc.PeerConnection) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.limits.MaxSessions > 0 && len(s.sessions) >= s.limits.MaxSessions {
+		return false
+	}
+	s.sessions[id] = peerConnection
+	return true
+}
+
+func (s *Server) get(id string) *webrtc.PeerConnection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sessions[id]
+}
+
+func (s *Server) remove(id string) *webrtc.PeerConnection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	peerConnection := s.sessions[id]
+	delete(s.sessions, id)
+	delete(s.candidates, id)
+	return peerConnection
+}
+
+// countCandidates adds n trickled candidates to session id and reports whether
+// it stays within Limits.MaxCandidatesPerSession, a limit that is not positive allows any number
+func (s *Server) countCandidates(id string, n int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.limits.MaxCandidatesPerSession > 0 && s.candidates[id]+n > s.limits.MaxCandidatesPerSession {
+		return false
+	}
+	s.candidates[id] += n
+	return true
+}
+
+// answerOffer applies the offer and returns an answer holding every local candidate
+func answerOffer(peerConnection *webrtc.PeerConnection, offer string) (*webrtc.SessionDescription, error) {
+	if err := peerConnection.SetRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeOffer,
+		SDP:  offer,
+	}); err != nil {
+		return nil, err
+	}
+
+	answer, err := peerConnection.CreateAnswer(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(peerConnection)
+	if err := peerConnection.SetLocalDescription(answer); err != nil {
+		return nil, err
+	}
+	<-gatherComplete
+
+	return peerConnection.LocalDescription(), nil
+}
+
+func closePeerConnection(peerConnection *webrtc.PeerConnection) {
+	if err := peerConnection.Close(); err != nil {
+		log.Println("cannot close peerConnection:", err)
+	}
+}
+
+func hasContentType(r *http.Request, expected string) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == expected
+}
@@ -0,0 +1,111 @@
+package whip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func TestServerClosesRejectedOffer(t *testing.T) {
+	sessions := make(chan *webrtc.PeerConnection, 1)
+	closed := make(chan *webrtc.PeerConnection, 1)
+	s := NewServer(webrtc.Configuration{}, func(id string, pc *webrtc.PeerConnection) error {
+		sessions <- pc
+		return nil
+	})
+	s.OnSessionClosed(func(id string, pc *webrtc.PeerConnection) {
+		closed <- pc
+	})
+
+	// onSession accepts the session, then the offer cannot be applied
+	r := httptest.NewRequest(http.MethodPost, "/whip", strings.NewReader("not an offer"))
+	r.Header.Set("Content-Type", sdpContentType)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	pc := <-sessions
+	select {
+	case got := <-closed:
+		if got != pc {
+			t.Fatal("OnSessionClosed got another PeerConnection")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnSessionClosed was not called")
+	}
+}
+
+// postOffer posts an offer of a new PeerConnection, closed with the test, to s
+func postOffer(t *testing.T, s *Server) *httptest.ResponseRecorder {
+	t.Helper()
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = pc.Close()
+	})
+	if _, err := pc.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendonly}); err != nil {
+		t.Fatal(err)
+	}
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/whip", strings.NewReader(offer.SDP))
+	r.Header.Set("Content-Type", sdpContentType)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestServerLimits(t *testing.T) {
+	closed := make(chan string, 1)
+	s := NewServer(webrtc.Configuration{}, nil)
+	s.OnSessionClosed(func(id string, pc *webrtc.PeerConnection) {
+		select {
+		case closed <- id:
+		default:
+		}
+	})
+	s.SetLimits(signaling.Limits{MaxBodyBytes: 8 << 10, MaxSessions: 1, ConnectTimeout: 200 * time.Millisecond})
+
+	r := httptest.NewRequest(http.MethodPost, "/whip", strings.NewReader(strings.Repeat("a", 8<<10+1)))
+	r.Header.Set("Content-Type", sdpContentType)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("large offer: status %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	first := postOffer(t, s)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first offer: status %d, want %d", first.Code, http.StatusCreated)
+	}
+	if w := postOffer(t, s); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("offer over MaxSessions: status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+
+	// Nobody answers the checks of the server, so the session never connects
+	select {
+	case id := <-closed:
+		if location := first.Header().Get("Location"); location != "/whip/"+id {
+			t.Fatalf("session %s closed, want the one at %s", id, location)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the session that did not connect was not closed")
+	}
+	if w := postOffer(t, s); w.Code != http.StatusCreated {
+		t.Fatalf("offer after the timeout: status %d, want %d", w.Code, http.StatusCreated)
+	}
+}
@@ -0,0 +1,71 @@
+package whip
+
+import (
+	"sync"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Resource is the session of a WHIP or WHEP endpoint, a Client or a whep.Client
+type Resource interface {
+	Trickle(candidates ...webrtc.ICECandidateInit) error
+	RestartICE(offer, remote webrtc.SessionDescription) (webrtc.SessionDescription, error)
+}
+
+// Session PATCHes the candidates of a PeerConnection to its Resource, once the
+// endpoint answered, and recovers from network changes with ICE restarts
+// PATCHed to the Resource as well. Descriptions go through the embedded Trickle.
+type Session struct {
+	*signaling.Trickle
+	restarter *signaling.ICERestarter
+
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// NewSession takes over OnICECandidate and OnConnectionStateChange of pc, see
+// OnConnectionStateChange
+func NewSession(pc *webrtc.PeerConnection, resource Resource) *Session {
+	s := &Session{done: make(chan struct{})}
+	s.Trickle = signaling.NewTrickle(pc, func(c webrtc.ICECandidateInit) error {
+		return resource.Trickle(c)
+	})
+
+	s.restarter = signaling.NewICERestarter(pc, func() error {
+		return s.RestartICE(func(offer webrtc.SessionDescription) error {
+			answer, err := resource.RestartICE(offer, *pc.RemoteDescription())
+			if err != nil {
+				return err
+			}
+			return s.SetRemoteDescription(answer)
+		})
+	}, signaling.ICERestartOptions{})
+	s.restarter.OnGiveUp(func() {
+		s.doneOnce.Do(func() { close(s.done) })
+	})
+
+	return s
+}
+
+// OnConnectionStateChange sets the handler for connection state changes of the PeerConnection
+func (s *Session) OnConnectionStateChange(f func(webrtc.PeerConnectionState)) {
+	s.restarter.OnConnectionStateChange(f)
+}
+
+// Done is closed once the connection did not recover, the session should be deleted then
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Token returns the token of cfg, or signs one with its api key that is valid for an hour.
+// It is empty when cfg has neither.
+func Token(cfg config.Config) (string, error) {
+	if cfg.Token != "" || !cfg.Authenticated() {
+		return cfg.Token, nil
+	}
+	return signaling.NewToken(cfg, time.Hour)
+}
@@ -0,0 +1,90 @@
+package whip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signaling"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func TestSessionPublishes(t *testing.T) {
+	server := httptest.NewServer(NewServer(webrtc.Configuration{}, nil))
+	t.Cleanup(server.Close)
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = pc.Close()
+	})
+	if _, err := pc.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendonly}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(server.URL+"/whip", "")
+	session := NewSession(pc, client)
+	connected := make(chan struct{})
+	session.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		if s == webrtc.PeerConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := client.Publish(offer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-connected:
+	case <-session.Done():
+		t.Fatal("session gave up")
+	case <-time.After(10 * time.Second):
+		t.Fatal("session did not connect")
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	cfg := config.Default()
+	if token, err := Token(cfg); err != nil || token != "" {
+		t.Fatalf("Token() of the dev key pair = %q, %v, want none", token, err)
+	}
+
+	cfg.Token = "static"
+	if token, err := Token(cfg); err != nil || token != "static" {
+		t.Fatalf("Token() = %q, %v, want the static token", token, err)
+	}
+
+	cfg.Token, cfg.ApiKey, cfg.ApiSecret = "", "key", "secret"
+	token, err := Token(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := signaling.NewAuthenticator(cfg).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	r := httptest.NewRequest(http.MethodPost, "/whip", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("signed token: status %d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -1,36 +1,72 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signaling"
+	"webrtc-demo/pkg/whip"
 
 	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/h264reader"
 )
 
+const h264FrameDuration = time.Millisecond * 33
+
 func main() {
-	peerConnection, err := webrtc.NewPeerConnection(webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{
-				URLs: []string{"stun:stun.l.google.com:19302"},
-			},
-		},
-	})
+	whipURL := flag.String("whip-url", "http://localhost:8080/whip", "WHIP endpoint the stream is published to.")
+	token := flag.String("token", "", "Bearer token for the WHIP endpoint.")
+	configPath := flag.String("config", "", "TOML config to issue the bearer token from when --token is empty, its [tls] table sets the trusted server certificate.")
+	videoAddr := flag.String("video-address", "127.0.0.1:5500", "UDP address an H264 Annex B stream is read from, e.g. ffmpeg ... -f h264 udp://127.0.0.1:5500")
+	iceFlags := config.NewICEFlags()
+	flag.Parse()
+
+	cfg, err := config.GetOptionalConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Flags given on the command line win over the config file and the environment
+	config.Override(&cfg, &cfg.Signaling.URL, "whip-url", *whipURL)
+	config.Override(&cfg, &cfg.Media.VideoAddress, "video-address", *videoAddr)
+	iceFlags.Apply(&cfg)
+	if cfg.Media.VideoCodec != webrtc.MimeTypeH264 {
+		log.Fatalf("the publisher reads H264 only, not %s", cfg.Media.VideoCodec)
+	}
+	if *token == "" {
+		if *token, err = whip.Token(cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	api, err := cfg.API()
+	if err != nil {
+		log.Fatal(err)
+	}
+	peerConnection, err := api.NewPeerConnection(cfg.WebRTCConfiguration())
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5500})
+	listener, err := net.ListenUDP("udp", resolveUDPAddr(cfg.Media.VideoAddress))
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer func() {
 		if err := listener.Close(); err != nil {
-			log.Fatal(err)
+			log.Println(err)
 		}
 	}()
 
-	h264Track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264}, "video", "webrtc-pion-demo")
+	h264Track, err := webrtc.NewTrackLocalStaticSample(cfg.Media.VideoCapability(), "video", "webrtc-pion-demo")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -49,6 +85,23 @@ func main() {
 		}
 	}()
 
+	client := whip.NewClient(cfg.Signaling.URL, *token)
+	// The token is the only credential, the config only decides which certificate is trusted
+	if client.HTTPClient, err = signaling.NewHTTPClient(config.Config{TLS: cfg.TLS}); err != nil {
+		log.Fatal(err)
+	}
+
+	// Candidates are PATCHed to the session once the WHIP server answered,
+	// network changes are recovered with ICE restarts PATCHed to it as well
+	session := whip.NewSession(peerConnection, client)
+	session.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		fmt.Printf("Peer Connection State has changed: %s\n", s.String())
+	})
+
+	// Tear the WHIP session down on Ctrl+C, or when the connection cannot be recovered
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
 	offer, err := peerConnection.CreateOffer(&webrtc.OfferOptions{
 		OfferAnswerOptions: webrtc.OfferAnswerOptions{
 			VoiceActivityDetection: true,
@@ -58,33 +111,84 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println("offer:", offer)
 
-	peerConnection.SetLocalDescription(offer)
+	if err := session.SetLocalDescription(offer); err != nil {
+		log.Fatal(err)
+	}
 
-	fmt.Println("local description:", peerConnection.CurrentLocalDescription())
-	fmt.Println("remote description:", peerConnection.RemoteDescription())
+	answer, err := client.Publish(offer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("whip session:", client.Location())
 
-	peerConnection.OnICEConnectionStateChange(func(is webrtc.ICEConnectionState) {
-		fmt.Printf("Connection State has changed %s \n", is.String())
+	if err := session.SetRemoteDescription(answer); err != nil {
+		log.Fatal(err)
+	}
 
-		if is == webrtc.ICEConnectionStateFailed {
-			if closeErr := peerConnection.Close(); closeErr != nil {
-				panic(closeErr)
-			}
+	go func() {
+		if err := writeH264(h264Track, listener); err != nil {
+			log.Println("video stream ended:", err)
 		}
-	})
+	}()
 
-	peerConnection.OnICECandidate(func(i *webrtc.ICECandidate) {
-		if i == nil {
-			return
-		}
+	select {
+	case <-interrupt:
+	case <-session.Done():
+		fmt.Println("Peer Connection did not recover")
+	}
+
+	if err := client.Close(); err != nil {
+		log.Println("cannot delete whip session:", err)
+	}
+	if err := peerConnection.Close(); err != nil {
+		log.Println("cannot close peerConnection:", err)
+	}
+}
 
-		remoteDescription := peerConnection.RemoteDescription()
-		if remoteDescription == nil {
+// writeH264 reads H264 NAL units from r and writes them to the track as samples
+func writeH264(track *webrtc.TrackLocalStaticSample, r io.Reader) error {
+	h264Reader, err := h264reader.NewReader(r)
+	if err != nil {
+		return err
+	}
+
+	spsAndPpsCache := []byte{}
+	ticker := time.NewTicker(h264FrameDuration)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
 
+		nal, err := h264Reader.NextNAL()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		nal.Data = append([]byte{0x00, 0x00, 0x00, 0x01}, nal.Data...)
+
+		if nal.UnitType == h264reader.NalUnitTypeSPS || nal.UnitType == h264reader.NalUnitTypePPS {
+			spsAndPpsCache = append(spsAndPpsCache, nal.Data...)
+			continue
+		} else if nal.UnitType == h264reader.NalUnitTypeCodedSliceIdr {
+			nal.Data = append(spsAndPpsCache, nal.Data...)
+			spsAndPpsCache = []byte{}
 		}
-	})
 
-	select {}
+		if err := track.WriteSample(media.Sample{
+			Data:     nal.Data,
+			Duration: h264FrameDuration,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+func resolveUDPAddr(addr string) *net.UDPAddr {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return udpAddr
 }
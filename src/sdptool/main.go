@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"webrtc-demo/pkg/sdputil"
+	"webrtc-demo/pkg/signal"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const usage = `Usage: sdptool <command> [flags] [file]
+
+Reads an offer or answer from file, or stdin when file is omitted. It may be
+raw SDP, the JSON of a webrtc.SessionDescription or a pkg/signal encoded blob.
+
+Commands:
+  inspect  print the media sections, codecs, directions, candidates and fingerprints
+  rewrite  restrict codecs, set bandwidth or strip candidates and print the result
+`
+
+// format is how a description was given, rewrite prints it back the same way
+type format int
+
+const (
+	formatRaw format = iota
+	formatJSON
+	formatSignal
+)
+
+func main() {
+	log.SetFlags(0)
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch command, args := flag.Arg(0), flag.Args()[1:]; command {
+	case "inspect":
+		err = inspect(args)
+	case "rewrite":
+		err = rewrite(args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func inspect(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Print the summary as JSON.")
+	sdpType := flags.String("type", "offer", "Type of raw SDP input: offer, pranswer or answer.")
+	_ = flags.Parse(args)
+
+	desc, _, err := readDescription(flags.Arg(0), *sdpType)
+	if err != nil {
+		return err
+	}
+
+	summary, err := sdputil.Inspect(desc)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summary)
+	}
+	fmt.Print(summary)
+	return nil
+}
+
+func rewrite(args []string) error {
+	flags := flag.NewFlagSet("rewrite", flag.ExitOnError)
+	codecs := flags.String("codecs", "", "Comma separated codecs audio and video are restricted to, e.g. H264,opus.")
+	bandwidth := flags.String("bandwidth", "", "Comma separated kind=bitrate to set b=AS/b=TIAS, e.g. video=2M,audio=64k. A bitrate without kind applies to every section.")
+	strip := flags.String("strip", "", "Comma separated candidate types to remove, e.g. host,srflx.")
+	sdpType := flags.String("type", "offer", "Type of raw SDP input: offer, pranswer or answer.")
+	_ = flags.Parse(args)
+
+	desc, inputFormat, err := readDescription(flags.Arg(0), *sdpType)
+	if err != nil {
+		return err
+	}
+
+	rewrites := []sdputil.Rewrite{}
+	if *codecs != "" {
+		rewrites = append(rewrites, sdputil.KeepCodecs(splitList(*codecs)...))
+	}
+	for _, spec := range splitList(*bandwidth) {
+		kind, value, ok := strings.Cut(spec, "=")
+		if !ok {
+			kind, value = "", spec
+		}
+		bitrate, err := sdputil.ParseBitrate(value)
+		if err != nil {
+			return err
+		}
+		rewrites = append(rewrites, sdputil.SetBandwidth(kind, bitrate))
+	}
+	if *strip != "" {
+		rewrites = append(rewrites, sdputil.StripCandidates(splitList(*strip)...))
+	}
+
+	desc, err = sdputil.Apply(desc, rewrites...)
+	if err != nil {
+		return err
+	}
+
+	return writeDescription(os.Stdout, desc, inputFormat)
+}
+
+// readDescription reads a description from path, or stdin when path is empty
+func readDescription(path, sdpType string) (webrtc.SessionDescription, format, error) {
+	desc := webrtc.SessionDescription{}
+
+	var (
+		in  []byte
+		err error
+	)
+	if path == "" || path == "-" {
+		in, err = io.ReadAll(os.Stdin)
+	} else {
+		in, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return desc, formatRaw, err
+	}
+	text := strings.TrimSpace(string(in))
+
+	switch {
+	case strings.HasPrefix(text, "v="):
+		desc.Type = webrtc.NewSDPType(sdpType)
+		desc.SDP = text + "\r\n"
+		return desc, formatRaw, nil
+	case strings.HasPrefix(text, "{"):
+		err = json.Unmarshal([]byte(text), &desc)
+		return desc, formatJSON, err
+	}
+
+	err = signal.Unmarshal(text, &desc)
+	return desc, formatSignal, err
+}
+
+func writeDescription(w io.Writer, desc webrtc.SessionDescription, f format) error {
+	switch f {
+	case formatJSON:
+		return json.NewEncoder(w).Encode(desc)
+	case formatSignal:
+		out, err := signal.Marshal(desc)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, out)
+		return err
+	}
+	_, err := fmt.Fprint(w, desc.SDP)
+	return err
+}
+
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/iceserver"
+	"webrtc-demo/pkg/signaling"
+	"webrtc-demo/pkg/whep"
+	"webrtc-demo/pkg/whip"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+)
+
+var errAlreadyPublishing = errors.New("a stream is already being published")
+
+// relay forwards the video of the WHIP publisher to every WHEP player
+type relay struct {
+	video *webrtc.TrackLocalStaticRTP
+
+	mu        sync.Mutex
+	publisher *webrtc.PeerConnection
+	mediaSSRC webrtc.SSRC
+}
+
+// newRelay forwards video of codec, publishers must send the same
+func newRelay(codec webrtc.RTPCodecCapability) (*relay, error) {
+	video, err := webrtc.NewTrackLocalStaticRTP(codec, "video", "webrtc-pion-demo")
+	if err != nil {
+		return nil, err
+	}
+	return &relay{video: video}, nil
+}
+
+// onPublish accepts a single WHIP session at a time, stopPublishing releases it
+// when its PeerConnection closes
+func (r *relay) onPublish(id string, pc *webrtc.PeerConnection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.publisher != nil {
+		return errAlreadyPublishing
+	}
+	r.publisher = pc
+
+	pc.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		fmt.Printf("whip session %s: got %s track %s\n", id, track.Codec().MimeType, track.ID())
+		if track.Kind() != webrtc.RTPCodecTypeVideo {
+			return
+		}
+
+		r.mu.Lock()
+		r.mediaSSRC = track.SSRC()
+		r.mu.Unlock()
+
+		defer r.stopPublishing(id, pc)
+
+		packets := 0
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			pkt, _, err := track.ReadRTP()
+			if err != nil {
+				fmt.Printf("whip session %s: track %s ended: %v\n", id, track.ID(), err)
+				return
+			}
+			if err := r.video.WriteRTP(pkt); err != nil {
+				fmt.Printf("whip session %s: cannot relay packet: %v\n", id, err)
+				return
+			}
+			packets++
+
+			select {
+			case <-ticker.C:
+				fmt.Printf("whip session %s: relayed %d packets\n", id, packets)
+			default:
+			}
+		}
+	})
+
+	return nil
+}
+
+// stopPublishing releases the publisher if it is still pc, a rejected second
+// publisher must not release the first
+func (r *relay) stopPublishing(_ string, pc *webrtc.PeerConnection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.publisher == pc {
+		r.publisher = nil
+		r.mediaSSRC = 0
+	}
+}
+
+// onPlay adds the relayed track to a WHEP session
+func (r *relay) onPlay(id string, pc *webrtc.PeerConnection) error {
+	rtpSender, err := pc.AddTrack(r.video)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("whep session %s: playing\n", id)
+
+	// Forward keyframe requests of late joiners to the publisher
+	go func() {
+		for {
+			packets, _, err := rtpSender.ReadRTCP()
+			if err != nil {
+				return
+			}
+			for _, pkt := range packets {
+				if _, ok := pkt.(*rtcp.PictureLossIndication); ok {
+					r.requestKeyframe()
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (r *relay) requestKeyframe() {
+	r.mu.Lock()
+	publisher, mediaSSRC := r.publisher, r.mediaSSRC
+	r.mu.Unlock()
+
+	if publisher == nil || mediaSSRC == 0 {
+		return
+	}
+	if err := publisher.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(mediaSSRC)}}); err != nil {
+		fmt.Println("cannot request keyframe:", err)
+	}
+}
+
+func main() {
+	addr := flag.String("address", ":8080", "Address that the WHIP and WHEP HTTP server is hosted on.")
+	configPath := flag.String("config", "", "TOML config whose api_key/api_secret authenticate publishers and players and whose [tls] table enables HTTPS, open endpoints when empty.")
+	iceFlags := config.NewICEFlags()
+	flag.Parse()
+
+	cfg, err := config.GetOptionalConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Flags given on the command line win over the config file and the environment
+	config.Override(&cfg, &cfg.Signaling.ListenAddress, "address", *addr)
+	iceFlags.Apply(&cfg)
+
+	// Host the STUN and TURN servers of the [stun] and [turn] tables of the config
+	// for publishers and players, they run until the server exits
+	if _, err := iceserver.Start(cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	config := cfg.WebRTCConfiguration()
+	api, err := cfg.API()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r, err := newRelay(cfg.Media.VideoCapability())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	whipServer := whip.NewServer(config, r.onPublish)
+	whipServer.SetAPI(api)
+	whipServer.OnSessionClosed(r.stopPublishing)
+	whepServer := whep.NewServer(config, r.onPlay)
+	whepServer.SetAPI(api)
+
+	mux := http.NewServeMux()
+	mux.Handle("/whip", whipServer)
+	mux.Handle("/whip/", whipServer)
+	mux.Handle("/whep", whepServer)
+	mux.Handle("/whep/", whepServer)
+
+	tlsConfig, err := signaling.ServerTLSConfig(cfg.TLS, cfg.Signaling.ListenAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("WHIP endpoint listening on %s\n", signaling.SignalingURL(cfg.TLS, "http", cfg.Signaling.ListenAddress, "/whip"))
+	fmt.Printf("WHEP endpoint listening on %s\n", signaling.SignalingURL(cfg.TLS, "http", cfg.Signaling.ListenAddress, "/whep"))
+	handler := signaling.DefaultLimits.Middleware(signaling.NewAuthenticator(cfg).Middleware(mux))
+	log.Fatal(signaling.ListenAndServe(cfg.Signaling.ListenAddress, handler, tlsConfig))
+}
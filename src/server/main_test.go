@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func newPeerConnection(t *testing.T) *webrtc.PeerConnection {
+	t.Helper()
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = pc.Close()
+	})
+	return pc
+}
+
+func TestRelayStopPublishing(t *testing.T) {
+	r, err := newRelay(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := newPeerConnection(t), newPeerConnection(t)
+
+	if err := r.onPublish("first", first); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.onPublish("second", second); !errors.Is(err, errAlreadyPublishing) {
+		t.Fatalf("second publisher: got %v, want %v", err, errAlreadyPublishing)
+	}
+
+	// The rejected session closing must not release the first one
+	r.stopPublishing("second", second)
+	if err := r.onPublish("second", second); !errors.Is(err, errAlreadyPublishing) {
+		t.Fatalf("after the second closed: got %v, want %v", err, errAlreadyPublishing)
+	}
+
+	r.stopPublishing("first", first)
+	if err := r.onPublish("second", second); err != nil {
+		t.Fatalf("after the first closed: %v", err)
+	}
+}
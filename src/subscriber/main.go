@@ -1,5 +1,156 @@
 package main
 
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"webrtc-demo/pkg/config"
+	"webrtc-demo/pkg/signaling"
+	"webrtc-demo/pkg/whep"
+	"webrtc-demo/pkg/whip"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+)
+
+const pliInterval = 3 * time.Second
+
 func main() {
+	whepURL := flag.String("whep-url", "http://localhost:8080/whep", "WHEP endpoint the stream is played from.")
+	token := flag.String("token", "", "Bearer token for the WHEP endpoint.")
+	configPath := flag.String("config", "", "TOML config to issue the bearer token from when --token is empty, its [tls] table sets the trusted server certificate.")
+	videoSink := flag.String("video-sink", "file://output.h264", "Where received video goes: discard, udp://host:port or file://path.")
+	audioSink := flag.String("audio-sink", "discard", "Where received audio goes: discard, udp://host:port or file://path.")
+	iceFlags := config.NewICEFlags()
+	flag.Parse()
+
+	cfg, err := config.GetOptionalConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Flags given on the command line win over the config file and the environment
+	config.Override(&cfg, &cfg.Signaling.URL, "whep-url", *whepURL)
+	config.Override(&cfg, &cfg.Media.VideoSink, "video-sink", *videoSink)
+	config.Override(&cfg, &cfg.Media.AudioSink, "audio-sink", *audioSink)
+	iceFlags.Apply(&cfg)
+	if *token == "" {
+		if *token, err = whip.Token(cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	api, err := cfg.API()
+	if err != nil {
+		log.Fatal(err)
+	}
+	peerConnection, err := api.NewPeerConnection(cfg.WebRTCConfiguration())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeVideo, webrtc.RTPCodecTypeAudio} {
+		if _, err := peerConnection.AddTransceiverFromKind(kind, webrtc.RTPTransceiverInit{
+			Direction: webrtc.RTPTransceiverDirectionRecvonly,
+		}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	peerConnection.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		codec := track.Codec()
+		fmt.Printf("Got %s track %s\n", codec.MimeType, track.ID())
+
+		spec := cfg.Media.AudioSink
+		if track.Kind() == webrtc.RTPCodecTypeVideo {
+			spec = cfg.Media.VideoSink
+
+			// Ask for a keyframe now and then, the stream is joined midway
+			go func() {
+				ticker := time.NewTicker(pliInterval)
+				defer ticker.Stop()
+				for range ticker.C {
+					if err := peerConnection.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())}}); err != nil {
+						return
+					}
+				}
+			}()
+		}
+
+		sink, err := newSink(spec, codec)
+		if err != nil {
+			log.Println("cannot create sink:", err)
+			return
+		}
+		defer func() {
+			if err := sink.Close(); err != nil {
+				log.Println("cannot close sink:", err)
+			}
+		}()
+
+		for {
+			pkt, _, err := track.ReadRTP()
+			if err != nil {
+				fmt.Printf("Track %s ended: %v\n", track.ID(), err)
+				return
+			}
+			if err := sink.WriteRTP(pkt); err != nil {
+				log.Println("cannot write to sink:", err)
+				return
+			}
+		}
+	})
+
+	client := whep.NewClient(cfg.Signaling.URL, *token)
+	// The token is the only credential, the config only decides which certificate is trusted
+	httpClient, err := signaling.NewHTTPClient(config.Config{TLS: cfg.TLS})
+	if err != nil {
+		log.Fatal(err)
+	}
+	client.SetHTTPClient(httpClient)
+
+	// Candidates are PATCHed to the session once the WHEP server answered,
+	// network changes are recovered with ICE restarts PATCHed to it as well
+	session := whip.NewSession(peerConnection, client)
+	session.OnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		fmt.Printf("Peer Connection State has changed: %s\n", s.String())
+	})
+
+	// Tear the WHEP session down on Ctrl+C, or when the connection cannot be recovered
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	offer, err := peerConnection.CreateOffer(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := session.SetLocalDescription(offer); err != nil {
+		log.Fatal(err)
+	}
+
+	answer, err := client.Subscribe(offer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("whep session:", client.Location())
+
+	if err := session.SetRemoteDescription(answer); err != nil {
+		log.Fatal(err)
+	}
+
+	select {
+	case <-interrupt:
+	case <-session.Done():
+		fmt.Println("Peer Connection did not recover")
+	}
 
+	if err := client.Close(); err != nil {
+		log.Println("cannot delete whep session:", err)
+	}
+	if err := peerConnection.Close(); err != nil {
+		log.Println("cannot close peerConnection:", err)
+	}
 }
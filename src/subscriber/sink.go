@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media/h264writer"
+	"github.com/pion/webrtc/v3/pkg/media/ivfwriter"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+)
+
+// rtpSink consumes the RTP packets of a remote track
+type rtpSink interface {
+	WriteRTP(*rtp.Packet) error
+	Close() error
+}
+
+// newSink creates the sink described by spec for a track negotiated with codec.
+//
+//	discard            drop every packet
+//	udp://host:port    forward the packets unchanged, e.g. to ffplay or gstreamer
+//	file://path        write the media to path: H264 as Annex B, VP8 as IVF, Opus as Ogg
+func newSink(spec string, codec webrtc.RTPCodecParameters) (rtpSink, error) {
+	switch {
+	case spec == "discard":
+		return discardSink{}, nil
+	case strings.HasPrefix(spec, "udp://"):
+		return newUDPSink(strings.TrimPrefix(spec, "udp://"))
+	case strings.HasPrefix(spec, "file://"):
+		return newFileSink(strings.TrimPrefix(spec, "file://"), codec)
+	}
+	return nil, fmt.Errorf("unknown sink %q", spec)
+}
+
+type discardSink struct{}
+
+func (discardSink) WriteRTP(*rtp.Packet) error { return nil }
+func (discardSink) Close() error               { return nil }
+
+type udpSink struct {
+	conn *net.UDPConn
+}
+
+func newUDPSink(addr string) (*udpSink, error) {
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, err
+	}
+	return &udpSink{conn: conn}, nil
+}
+
+func (s *udpSink) WriteRTP(pkt *rtp.Packet) error {
+	b, err := pkt.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.Write(b)
+	return err
+}
+
+func (s *udpSink) Close() error {
+	return s.conn.Close()
+}
+
+func newFileSink(path string, codec webrtc.RTPCodecParameters) (rtpSink, error) {
+	switch strings.ToLower(codec.MimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return h264writer.New(path)
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return ivfwriter.New(path)
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		return oggwriter.New(path, codec.ClockRate, codec.Channels)
+	}
+	return nil, fmt.Errorf("cannot write %s to a file", codec.MimeType)
+}